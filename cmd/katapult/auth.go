@@ -1,49 +1,240 @@
 package main
 
 import (
-	"github.com/krystal/go-katapult/core"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/krystal/go-katapult"
 	"github.com/krystal/katapult-cli/config"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 const authFormat = `Successfully authenticated. Here is your current list of organizations:
 {{ Table (StringSlice "Name" "Subdomain") (MultipleRows . "Name" "SubDomain") }}`
 
-func authCommand(conf *config.Config) *cobra.Command {
-	return &cobra.Command{
-		Use:   "auth",
-		Args:  cobra.ExactArgs(1),
+// Used to create an organizations client with the token in the config. This is used by login since the token
+// is only known when the command runs.
+type orgsClientFactory func(conf *config.Config) (organisationsListClient, error)
+
+// Used to check if a file descriptor is a terminal, and to read from one without showing what is typed. These are
+// variables so that a terminal can be used in tests.
+var (
+	isTerminalFd = term.IsTerminal
+	readPassword = term.ReadPassword
+)
+
+// Used to read the token for login. This is the argument if there is one, otherwise the file set by --token-file
+// or stdin. If stdin is a terminal, the token is prompted for.
+func readLoginToken(cmd *cobra.Command, args []string) (string, error) {
+	if len(args) == 1 {
+		return args[0], nil
+	}
+
+	var b []byte
+	var err error
+	tokenFile, _ := cmd.Flags().GetString("token-file")
+	if tokenFile == "" || tokenFile == "-" {
+		if f, ok := cmd.InOrStdin().(*os.File); ok && isTerminalFd(int(f.Fd())) {
+			// Prompt for the token rather than waiting for stdin to be closed, and don't show it as it is typed.
+			_, _ = fmt.Fprint(cmd.ErrOrStderr(), "API token: ")
+			b, err = readPassword(int(f.Fd()))
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr())
+		} else {
+			b, err = ioutil.ReadAll(cmd.InOrStdin())
+		}
+	} else {
+		b, err = ioutil.ReadFile(tokenFile)
+	}
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return "", errors.New("no API token was given, pass it as an argument, with --token-file or on stdin")
+	}
+	return token, nil
+}
+
+func authLoginCmd(conf *config.Config, newOrgsClient orgsClientFactory) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login",
+		Args:  cobra.MaximumNArgs(1),
 		Short: "Authenticate user",
-		Long:  "Authenticates the user with a token. The argument should be an API token.",
+		Long: "Authenticates the user with a token. The token can be passed as an argument, read from the file " +
+			"set with --token-file, or read from stdin. If stdin is a terminal, the token is prompted for without " +
+			"showing it. The token is saved for the active profile.",
 		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
 			// Get the token.
-			token := args[0]
+			tokenFile, _ := cmd.Flags().GetString("token-file")
+			if len(args) == 1 && tokenFile != "" {
+				return nil, errors.New("the token cannot be passed as an argument when --token-file is set")
+			}
+			token, err := readLoginToken(cmd, args)
+			if err != nil {
+				return nil, err
+			}
 
 			// Set the token in the config. This is okay to mutate since we are going
 			// to exit after this in any case.
 			conf.APIToken = token
 
 			// Check if the config works.
-			c, err := newClient(conf)
+			client, err := newOrgsClient(conf)
 			if err != nil {
 				return nil, err
 			}
-			orgs, _, err := core.NewOrganizationsClient(c).List(cmd.Context())
+			orgs, _, err := client.List(cmd.Context())
 			if err != nil {
 				return nil, err
 			}
 
-			// Write the config.
-			conf.Set("api_token", token)
+			// Save the token for the active profile. This uses the best credential store available.
+			store, err := conf.SaveToken(token)
+			if err != nil {
+				return nil, err
+			}
 			if err = conf.WriteConfig(); err != nil {
 				return nil, err
 			}
+			if isTextOutput() {
+				_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Token saved to the %s credential store.\n", store)
+			}
 
 			// Return the output.
 			return &genericOutput{
 				item:                orgs,
 				defaultTextTemplate: authFormat,
+				columns:             organizationsListColumns,
 			}, nil
 		}),
+		Annotations: map[string]string{noTokenAnnotation: "true"},
 	}
+	cmd.Flags().String("token-file", "", "Read the token from a file. Use - for stdin.")
+	return cmd
+}
+
+// Defines the result of checking the configured token.
+type authStatus struct {
+	Profile       string                `json:"profile" yaml:"profile"`
+	APIURL        string                `json:"api_url" yaml:"api_url"`
+	ConfigFile    string                `json:"config_file" yaml:"config_file"`
+	TokenSource   string                `json:"token_source" yaml:"token_source"`
+	Organizations []*authStatusOrgEntry `json:"organizations" yaml:"organizations"`
+}
+
+// Defines an organization in the auth status.
+type authStatusOrgEntry struct {
+	ID        string `json:"id" yaml:"id"`
+	Name      string `json:"name" yaml:"name"`
+	SubDomain string `json:"subdomain" yaml:"subdomain"`
+}
+
+const authStatusFormat = `Authenticated with {{ .APIURL }} using the {{ .Profile }} profile.
+Config file: {{ if .ConfigFile }}{{ .ConfigFile }}{{ else }}none{{ end }}
+Token source: {{ .TokenSource }}
+{{ Table (StringSlice "Name" "Subdomain") (MultipleRows .Organizations "Name" "SubDomain") }}`
+
+// Used to build a command which checks the configured token. This is used for both auth status and whoami.
+func authStatusCmd(conf *config.Config, client organisationsListClient, use, short string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Args:  cobra.NoArgs,
+		Short: short,
+		Long: "Checks the configured API token and shows the profile, API URL and config file which are in use. " +
+			"The command fails if there isn't a token or the token is not valid.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			profile := conf.ProfileName()
+			source := conf.TokenSource()
+			if source == "" {
+				return nil, fmt.Errorf("no API token is set for the %s profile, run auth login to set one", profile)
+			}
+			orgs, _, err := client.List(cmd.Context())
+			if err != nil {
+				return nil, fmt.Errorf("the API token for the %s profile is not valid: %w", profile, err)
+			}
+
+			apiURL := conf.APIURL
+			if apiURL == "" {
+				apiURL = katapult.DefaultURL.String()
+			}
+			status := &authStatus{
+				Profile:       profile,
+				APIURL:        apiURL,
+				ConfigFile:    conf.ConfigFileUsed(),
+				TokenSource:   source,
+				Organizations: make([]*authStatusOrgEntry, len(orgs)),
+			}
+			for i, org := range orgs {
+				status.Organizations[i] = &authStatusOrgEntry{ID: org.ID, Name: org.Name, SubDomain: org.SubDomain}
+			}
+			return &genericOutput{
+				item:                status,
+				defaultTextTemplate: authStatusFormat,
+			}, nil
+		}),
+	}
+}
+
+func authLogoutCmd(conf *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout",
+		Args:  cobra.NoArgs,
+		Short: "Remove the saved token",
+		Long: "Removes the API token of the active profile from its credential store and the config file. Tokens " +
+			"set with --api-token or KATAPULT_API_TOKEN are not affected.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			store, err := conf.DeleteToken()
+			if err != nil {
+				return nil, err
+			}
+			if err = conf.WriteConfig(); err != nil {
+				return nil, err
+			}
+			return &genericOutput{
+				item:                map[string]string{"profile": conf.ProfileName(), "credential_store": store},
+				defaultTextTemplate: "Removed the token of the {{ .profile }} profile.\n",
+			}, nil
+		}),
+		Annotations: map[string]string{noTokenAnnotation: "true"},
+	}
+}
+
+func whoamiCommand(conf *config.Config, client organisationsListClient) *cobra.Command {
+	return authStatusCmd(conf, client, "whoami", "Show the profile and organizations in use")
+}
+
+func authCommand(
+	conf *config.Config, client organisationsListClient, newOrgsClient orgsClientFactory,
+) *cobra.Command {
+	login := authLoginCmd(conf, newOrgsClient)
+	cmd := &cobra.Command{
+		Use:   "auth",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Manage authentication",
+		Long: "Log in and out, and check the API token which is in use. Passing a token straight to auth is " +
+			"deprecated, use auth login instead.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+
+			// Before login was added, the token was passed straight to auth. This still works for now.
+			_, _ = fmt.Fprintln(cmd.ErrOrStderr(),
+				"Warning: katapult auth <token> is deprecated, use katapult auth login <token> instead.")
+			return login.RunE(cmd, args)
+		},
+		Annotations: map[string]string{noTokenAnnotation: "true"},
+	}
+
+	cmd.AddCommand(
+		login,
+		authStatusCmd(conf, client, "status", "Check the configured token"),
+		authLogoutCmd(conf),
+	)
+
+	return cmd
 }
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krystal/katapult-cli/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuth_Login(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args      []string
+		legacy    bool
+		stdin     string
+		tokenFile string
+		throws    string
+		wantErr   string
+	}{
+		{
+			name: "token argument",
+			args: []string{"abc"},
+		},
+		{
+			name:   "token argument without login",
+			args:   []string{"abc"},
+			legacy: true,
+		},
+		{
+			name:  "token from stdin",
+			stdin: "abc\n",
+		},
+		{
+			name:      "token file",
+			tokenFile: "abc\n",
+		},
+		{
+			name:    "token file and argument",
+			args:    []string{"abc", "--token-file", "token.txt"},
+			wantErr: "the token cannot be passed as an argument when --token-file is set",
+		},
+		{
+			name:    "no token",
+			wantErr: "no API token was given, pass it as an argument, with --token-file or on stdin",
+		},
+		{
+			name:    "invalid token",
+			args:    []string{"abc"},
+			throws:  "unauthorized",
+			wantErr: "unauthorized",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, fp := loadTestConfig(t, "credential_store: plaintext\n")
+			args := append([]string{"login"}, tt.args...)
+			stderr := "Token saved to the plaintext credential store.\n"
+			if tt.legacy {
+				args = tt.args
+				stderr = "Warning: katapult auth <token> is deprecated, use katapult auth login <token> instead.\n" +
+					stderr
+			}
+			if tt.tokenFile != "" {
+				path := filepath.Join(t.TempDir(), "token.txt")
+				require.NoError(t, ioutil.WriteFile(path, []byte(tt.tokenFile), 0o600))
+				args = append(args, "--token-file", path)
+			}
+
+			var usedToken string
+			cmd := authCommand(conf, nil, func(conf *config.Config) (organisationsListClient, error) {
+				usedToken = conf.APIToken
+				return mockOrganizationsListClient{orgs: fixtureOrganizations, throws: tt.throws}, nil
+			})
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			cmd.SetArgs(args)
+			assertCobraCommand(t, cmd, tt.wantErr, stderr)
+			if tt.wantErr != "" {
+				return
+			}
+			assert.Equal(t, "abc", usedToken)
+			assert.Equal(t, "api_token: abc\ncredential_store: plaintext\n", readFile(t, fp))
+		})
+	}
+}
+
+func TestAuth_LoginPrompt(t *testing.T) {
+	oldIsTerminalFd, oldReadPassword := isTerminalFd, readPassword
+	defer func() { isTerminalFd, readPassword = oldIsTerminalFd, oldReadPassword }()
+	isTerminalFd = func(int) bool { return true }
+	readPassword = func(int) ([]byte, error) { return []byte("abc"), nil }
+
+	// Stdin needs to be a file to be a terminal.
+	stdin, err := ioutil.TempFile(t.TempDir(), "stdin")
+	require.NoError(t, err)
+	defer stdin.Close()
+
+	conf, fp := loadTestConfig(t, "credential_store: plaintext\n")
+	cmd := authCommand(conf, nil, func(conf *config.Config) (organisationsListClient, error) {
+		return mockOrganizationsListClient{orgs: fixtureOrganizations}, nil
+	})
+	cmd.SetIn(stdin)
+	cmd.SetArgs([]string{"login"})
+	assertCobraCommand(t, cmd, "", "API token: \nToken saved to the plaintext credential store.\n")
+	assert.Equal(t, "api_token: abc\ncredential_store: plaintext\n", readFile(t, fp))
+}
+
+func TestAuth_Status(t *testing.T) {
+	tests := []struct {
+		name string
+
+		apiToken string
+		apiURL   string
+		throws   string
+		output   string
+		wantErr  string
+	}{
+		{
+			name:     "authenticated",
+			apiToken: "abc",
+		},
+		{
+			name:     "custom API URL",
+			apiToken: "abc",
+			apiURL:   "https://api.katapult.example",
+		},
+		{
+			name:     "json output",
+			apiToken: "abc",
+			output:   "json",
+		},
+		{
+			name:    "no token",
+			wantErr: "no API token is set for the default profile, run auth login to set one",
+		},
+		{
+			name:     "invalid token",
+			apiToken: "abc",
+			throws:   "unauthorized",
+			wantErr:  "the API token for the default profile is not valid: unauthorized",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, err := config.New()
+			require.NoError(t, err)
+			conf.APIToken = tt.apiToken
+			conf.APIURL = tt.apiURL
+			client := mockOrganizationsListClient{orgs: fixtureOrganizations, throws: tt.throws}
+			cmd := authCommand(conf, client, nil)
+			cmd.SetArgs([]string{"status"})
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			outputFlag = ""
+		})
+	}
+}
+
+func TestAuth_Logout(t *testing.T) {
+	tests := []struct {
+		name string
+
+		content string
+		want    string
+	}{
+		{
+			name:    "plaintext",
+			content: "api_token: abc\norganization: loge\n",
+			want:    "organization: loge\n",
+		},
+		{
+			name:    "profile",
+			content: "api_token: abc\ncurrent_profile: staging\nprofiles:\n  staging:\n    api_token: def\n",
+			want:    "api_token: abc\ncurrent_profile: staging\nprofiles:\n  staging:\n    api_token: \"\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, fp := loadTestConfig(t, tt.content)
+			cmd := authCommand(conf, nil, nil)
+			cmd.SetArgs([]string{"logout"})
+			assertCobraCommand(t, cmd, "", "")
+			assert.Equal(t, tt.want, readFile(t, fp))
+			assert.Empty(t, conf.APIToken)
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Defines the environment variables which record API requests to a cassette or replay them from one.
+const (
+	recordEnv = "KATAPULT_RECORD"
+	replayEnv = "KATAPULT_REPLAY"
+)
+
+// Defines the error returned when a request isn't in the cassette being replayed. It is not retried since the
+// cassette won't change.
+var errNotRecorded = errors.New("no recorded response")
+
+// Defines the cassette transport which the API client is built on when recording or replaying. It is shared by
+// every client which a command makes. If this is nil, the client connects to the API directly.
+var baseTransport http.RoundTripper
+
+// Defines a file of recorded API requests and their responses. Secrets are redacted before they are saved, so
+// a cassette can be attached to a bug report.
+type cassette struct {
+	Interactions []*interaction `json:"interactions"`
+}
+
+// Defines a recorded request and the response to it.
+type interaction struct {
+	Request  cassetteRequest  `json:"request"`
+	Response cassetteResponse `json:"response"`
+}
+
+// Defines a recorded request.
+type cassetteRequest struct {
+	Method string          `json:"method"`
+	URL    string          `json:"url"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// Defines a recorded response. JSON bodies are saved as JSON, and anything else is saved as a string.
+type cassetteResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// Defines the response headers which are not recorded. The length changes when the body is redacted.
+var skippedCassetteHeaders = map[string]bool{
+	"Content-Length": true,
+	"Set-Cookie":     true,
+}
+
+// Used to get the body of a recorded response as it was sent.
+func (r *cassetteResponse) body() []byte {
+	var s string
+	if !strings.Contains(r.Headers["Content-Type"], "json") && json.Unmarshal(r.Body, &s) == nil {
+		return []byte(s)
+	}
+	return r.Body
+}
+
+// Used to get the key which a request is matched with when replaying. The host is left out so that a cassette
+// can be replayed against any API URL.
+func cassetteKey(method, rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		rawURL = u.RequestURI()
+	}
+	return method + " " + rawURL
+}
+
+// Used to create the transport for KATAPULT_RECORD or KATAPULT_REPLAY. Nil is returned if neither is set.
+// Recorded requests are sent with the next transport, and the secrets are redacted from anything which is recorded.
+func newCassetteTransport(next http.RoundTripper, record, replay string, secrets ...string) (http.RoundTripper, error) {
+	switch {
+	case record != "" && replay != "":
+		return nil, errors.New(recordEnv + " and " + replayEnv + " cannot both be set")
+	case record != "":
+		return newRecordTransport(next, record, secrets...)
+	case replay != "":
+		return newReplayTransport(replay, secrets...)
+	default:
+		return nil, nil
+	}
+}
+
+// Used to record each request and its response to a cassette. The cassette is saved after every response, so
+// it is kept even if the command fails part of the way through.
+type recordTransport struct {
+	next http.RoundTripper
+	path string
+	redactor
+
+	mu       sync.Mutex
+	cassette cassette
+}
+
+// Used to create a record transport. The cassette is created straight away so a bad path is found before any
+// requests are made.
+func newRecordTransport(next http.RoundTripper, path string, secrets ...string) (*recordTransport, error) {
+	t := &recordTransport{
+		next:     next,
+		path:     path,
+		redactor: newRedactor(secrets...),
+		cassette: cassette{Interactions: []*interaction{}},
+	}
+	if err := t.save(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Used to write the cassette to its file. It can hold details of an organization, so only the user can read it.
+func (t *recordTransport) save() error {
+	b := &bytes.Buffer{}
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(t.cassette); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(t.path, b.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip is used to send the request and record it.
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	i := &interaction{Request: cassetteRequest{Method: req.Method, URL: t.redactURL(req.URL)}}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			_ = body.Close()
+			i.Request.Body = t.redactBody(b)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the body so it can be recorded, and then give the response a copy of it.
+	b, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+	i.Response = cassetteResponse{Status: resp.StatusCode, Headers: map[string]string{}, Body: t.redactBody(b)}
+	for k := range resp.Header {
+		if !skippedCassetteHeaders[k] {
+			i.Response.Headers[k] = t.redact(resp.Header.Get(k))
+		}
+	}
+
+	// Requests can run at once when lists are fetched, so only save one at a time.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cassette.Interactions = append(t.cassette.Interactions, i)
+	if err = t.save(); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Used to serve the responses in a cassette without using the network. Requests are matched by their method and
+// URL, and requests which were recorded more than once get their responses in the order they were recorded.
+type replayTransport struct {
+	path string
+	redactor
+
+	mu       sync.Mutex
+	cassette cassette
+	used     []bool
+}
+
+// Used to create a replay transport from a cassette file.
+func newReplayTransport(path string, secrets ...string) (*replayTransport, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	t := &replayTransport{path: path, redactor: newRedactor(secrets...)}
+	if err = json.Unmarshal(b, &t.cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+	t.used = make([]bool, len(t.cassette.Interactions))
+	return t, nil
+}
+
+// RoundTrip is used to find the recorded response to the request.
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+	u := t.redactURL(req.URL)
+	key := cassetteKey(req.Method, u)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for n, i := range t.cassette.Interactions {
+		if t.used[n] || cassetteKey(i.Request.Method, i.Request.URL) != key {
+			continue
+		}
+		t.used[n] = true
+
+		header := http.Header{}
+		for k, v := range i.Response.Headers {
+			header.Set(k, v)
+		}
+		body := i.Response.body()
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.Status, http.StatusText(i.Response.Status)),
+			StatusCode:    i.Response.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s %s in %s", errNotRecorded, req.Method, u, t.path)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krystal/katapult-cli/config"
+	"github.com/krystal/katapult-cli/internal/golden"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_recordTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/core/v1/organizations":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(requestIDHeader, "req_1")
+			_, _ = w.Write([]byte(`{"organizations":[{"id":"org_1"}],"api_token":"abc"}`))
+		case "/core/v1/ssh_keys":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("created with secret-token"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	// Record some requests to the server.
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rt, err := newRecordTransport(http.DefaultTransport, path, "secret-token")
+	require.NoError(t, err)
+	send := func(rt http.RoundTripper, method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret-token")
+		resp, err := (&http.Client{Transport: rt}).Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(b)
+	}
+	status, body := send(rt, http.MethodGet, "/core/v1/organizations?token=secret-token&page=1", "")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, `{"organizations":[{"id":"org_1"}],"api_token":"abc"}`, body)
+	status, body = send(rt, http.MethodPost, "/core/v1/ssh_keys", `{"name":"test","password":"hunter2"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created with secret-token", body)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	got := strings.ReplaceAll(string(b), srv.URL, "http://api.test")
+	for _, secret := range []string{"secret-token", "abc", "hunter2", "Bearer"} {
+		assert.NotContains(t, got, secret)
+	}
+	assert.Contains(t, got, `"url": "http://api.test/core/v1/organizations?page=1&token=********"`)
+	assert.Contains(t, got, `"X-Request-Id": "req_1"`)
+	assert.NotContains(t, got, "Content-Length")
+
+	// Replay them with the server closed. The token may be different when the cassette is replayed, and
+	// requests are matched without the host.
+	srv.Close()
+	replay, err := newReplayTransport(path, "other-token")
+	require.NoError(t, err)
+	status, body = send(replay, http.MethodPost, "/core/v1/ssh_keys", `{"name":"test"}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "created with ********", body)
+	req, err := http.NewRequest(http.MethodGet,
+		"https://example.com/core/v1/organizations?page=1&token=other-token", nil)
+	require.NoError(t, err)
+	resp, err := replay.RoundTrip(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	b, err = ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"organizations":[{"id":"org_1"}],"api_token":"********"}`, string(b))
+
+	// Each response is only served once.
+	_, err = replay.RoundTrip(req)
+	assert.EqualError(t, err,
+		"no recorded response for GET https://example.com/core/v1/organizations?page=1&token=******** in "+path)
+}
+
+func Test_newCassetteTransport(t *testing.T) {
+	dir := t.TempDir()
+	rt, err := newCassetteTransport(http.DefaultTransport, "", "")
+	require.NoError(t, err)
+	assert.Nil(t, rt)
+
+	_, err = newCassetteTransport(http.DefaultTransport, "a.json", "b.json")
+	assert.EqualError(t, err, "KATAPULT_RECORD and KATAPULT_REPLAY cannot both be set")
+
+	_, err = newCassetteTransport(http.DefaultTransport, filepath.Join(dir, "missing", "a.json"), "")
+	assert.Error(t, err)
+
+	_, err = newCassetteTransport(http.DefaultTransport, "", filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, ioutil.WriteFile(invalid, []byte("{"), 0o600))
+	_, err = newCassetteTransport(http.DefaultTransport, "", invalid)
+	assert.EqualError(t, err, "invalid cassette "+invalid+": unexpected end of JSON input")
+}
+
+// Used to replace the file with a pipe until the returned function is called, which returns what was written.
+func capturePipe(t *testing.T, f **os.File) func() string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	out := make(chan string)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		out <- string(b)
+	}()
+	old := *f
+	*f = w
+	return func() string {
+		*f = old
+		_ = w.Close()
+		return <-out
+	}
+}
+
+// Used to run the whole CLI with the arguments, returning what was written to stdout and stderr. An empty config
+// file is used unless KATAPULT_CONFIG is in env.
+func runCLI(t *testing.T, env map[string]string, args ...string) (stdout, stderr string, err error) {
+	t.Helper()
+	if _, ok := env[config.ConfigEnv]; !ok {
+		confPath := filepath.Join(t.TempDir(), "katapult.yaml")
+		require.NoError(t, ioutil.WriteFile(confPath, []byte{}, 0o600))
+		env[config.ConfigEnv] = confPath
+	}
+	for k, v := range env {
+		os.Setenv(k, v)
+	}
+	oldArgs := os.Args
+	defer func() {
+		for k := range env {
+			os.Unsetenv(k)
+		}
+		os.Args = oldArgs
+		baseTransport = nil
+		outputFlag = ""
+		defaultOrganization = ""
+	}()
+
+	os.Args = append([]string{"katapult"}, args...)
+	getStdout, getStderr := capturePipe(t, &os.Stdout), capturePipe(t, &os.Stderr)
+	err = run()
+	return getStdout(), getStderr(), err
+}
+
+func TestRun_Replay(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		wantErr string
+	}{
+		{
+			name: "dc list",
+			args: []string{"dc", "list"},
+		},
+		{
+			name: "dc get",
+			args: []string{"dc", "get", "POG1"},
+		},
+		{
+			name: "vm list",
+			args: []string{"vm", "list", "loge", "-o", "json"},
+		},
+		{
+			name: "vm list columns",
+			args: []string{"vm", "list", "loge", "--columns", "name,state", "--columns", "id"},
+		},
+		{
+			name: "vm list csv",
+			args: []string{"vm", "list", "loge", "-o", "csv", "--columns", "id,name", "--filter", "name~=."},
+		},
+		{
+			name:    "not recorded",
+			args:    []string{"org", "list"},
+			wantErr: "no recorded response for GET",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, _, err := runCLI(t, map[string]string{
+				"KATAPULT_API_TOKEN": "test-token",
+				replayEnv:            filepath.Join("testdata", "TestRun_Replay", "cassette.json"),
+			}, tt.args...)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if golden.Update() {
+				golden.Set(t, []byte(stdout))
+			}
+			assert.Equal(t, string(golden.Get(t)), stdout)
+		})
+	}
+}
+
+func TestRun_Record(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data_centers":[{"id":"dc_1","name":"Pogland","permalink":"POG1"}]}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	stdout, _, err := runCLI(t, map[string]string{
+		"KATAPULT_API_TOKEN": "test-token",
+		"KATAPULT_API_URL":   srv.URL,
+		recordEnv:            path,
+	}, "dc", "list", "-o", "json")
+	require.NoError(t, err)
+	assert.Contains(t, stdout, `"permalink": "POG1"`)
+
+	b, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"url": "`+srv.URL+`/core/v1/data_centers"`)
+	assert.NotContains(t, string(b), "test-token")
+	assert.True(t, bytes.Contains(b, []byte(`"permalink": "POG1"`)))
+}
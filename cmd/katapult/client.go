@@ -1,14 +1,102 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
 	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/krystal/go-katapult"
 	"github.com/krystal/go-katapult/core"
 	"github.com/krystal/katapult-cli/config"
 )
 
+// Defines where warnings about insecure settings are written. This is changed in tests.
+var warningOutput io.Writer = os.Stderr
+
+// Used to create the transport which connects to the API, with the proxy and TLS settings in the config. The
+// default transport is copied so that HTTPS_PROXY and the system certificates are still used when these aren't set.
+func newHTTPTransport(conf *config.Config) (http.RoundTripper, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.HTTPSProxy != "" {
+		proxyURL, err := url.Parse(conf.HTTPSProxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid HTTPS proxy: %s", conf.HTTPSProxy)
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	insecure := false
+	if conf.InsecureSkipVerify != "" {
+		var err error
+		insecure, err = strconv.ParseBool(conf.InsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("invalid insecure skip verify: %s", conf.InsecureSkipVerify)
+		}
+	}
+	if conf.CABundle == "" && conf.ClientCert == "" && conf.ClientKey == "" && !insecure {
+		return t, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conf.CABundle != "" {
+		// Add the bundle to the system certificates, so that the API can still be reached without the proxy.
+		pem, err := ioutil.ReadFile(conf.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", conf.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	switch {
+	case conf.ClientCert != "" && conf.ClientKey != "":
+		cert, err := tls.LoadX509KeyPair(conf.ClientCert, conf.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case conf.ClientCert != "" || conf.ClientKey != "":
+		return nil, errors.New("client_cert and client_key must be set together")
+	}
+	t.TLSClientConfig = tlsConfig
+	if insecure {
+		tlsConfig.InsecureSkipVerify = true //nolint:gosec // This is only turned on when asked for.
+		return &insecureTransport{next: t}, nil
+	}
+	return t, nil
+}
+
+// Used to warn that the certificate of the API isn't checked. The warning is written the first time a request is
+// sent, so commands which don't use the API don't print it.
+type insecureTransport struct {
+	next http.RoundTripper
+	once sync.Once
+}
+
+// RoundTrip is used to write the warning if it hasn't been yet, and then send the request.
+func (t *insecureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() {
+		_, _ = fmt.Fprintln(warningOutput, "WARNING: TLS certificate verification is turned off with "+
+			"insecure_skip_verify. Anyone between you and the API can read and change your requests, "+
+			"including your API token. Only use this to debug a connection.")
+	})
+	return t.next.RoundTrip(req)
+}
+
 // Create a new Katapult client.
 func newClient(conf *config.Config) (core.RequestMaker, error) {
 	a := []katapult.Opt{katapult.WithAPIKey(conf.APIToken)}
@@ -19,6 +107,49 @@ func newClient(conf *config.Config) (core.RequestMaker, error) {
 		}
 		a = append(a, katapult.WithBaseURL(apiURL))
 	}
+
+	// Retry requests which are rate limited or fail with a transient error.
+	retries := defaultRetries
+	if conf.Retries != "" {
+		n, err := strconv.Atoi(conf.Retries)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid retries: %s", conf.Retries)
+		}
+		retries = n
+	}
+	maxWait := defaultRetryMaxWait
+	if conf.RetryMaxWait != "" {
+		d, err := time.ParseDuration(conf.RetryMaxWait)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid retry max wait: %s", conf.RetryMaxWait)
+		}
+		maxWait = d
+	}
+
+	// Connect to the API directly, unless a cassette is being recorded or replayed.
+	transport := baseTransport
+	if transport == nil {
+		var err error
+		if transport, err = newHTTPTransport(conf); err != nil {
+			return nil, err
+		}
+	}
+
+	// Trace each attempt if --debug is set.
+	debug, err := newDebugTransport(transport, debugFlag, logFormatFlag, conf.APIToken)
+	if err != nil {
+		return nil, err
+	}
+	if debug != nil {
+		transport = debug
+	}
+
+	// The timeout is applied to each attempt by the retry transport rather than by the client, so that waiting
+	// between attempts doesn't use it up.
+	a = append(a, katapult.WithHTTPClient(&http.Client{
+		Transport: newRetryTransport(transport, retries, maxWait, katapult.DefaultTimeout),
+	}))
+
 	c, err := katapult.New(a...)
 	if err != nil {
 		return nil, err
@@ -1,7 +1,28 @@
 package main
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/krystal/go-katapult"
 	"github.com/krystal/katapult-cli/config"
@@ -13,9 +34,13 @@ func Test_newClient(t *testing.T) {
 	tests := []struct {
 		name string
 
-		apiToken string
-		apiURL   string
-		wantErr  string
+		apiToken     string
+		apiURL       string
+		retries      string
+		retryMaxWait string
+		wantRetries  int
+		wantMaxWait  time.Duration
+		wantErr      string
 	}{
 		{
 			name:     "empty API URL",
@@ -31,12 +56,37 @@ func Test_newClient(t *testing.T) {
 			apiToken: "test",
 			apiURL:   "https://example.com",
 		},
+		{
+			name:         "retries",
+			apiToken:     "test",
+			retries:      "5",
+			retryMaxWait: "10s",
+			wantRetries:  5,
+			wantMaxWait:  10 * time.Second,
+		},
+		{
+			name:     "no retries",
+			apiToken: "test",
+			retries:  "0",
+		},
+		{
+			name:    "invalid retries",
+			retries: "-1",
+			wantErr: "invalid retries: -1",
+		},
+		{
+			name:         "invalid retry max wait",
+			retryMaxWait: "soon",
+			wantErr:      "invalid retry max wait: soon",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c, err := newClient(&config.Config{
-				APIToken: tt.apiToken,
-				APIURL:   tt.apiURL,
+				APIToken:     tt.apiToken,
+				APIURL:       tt.apiURL,
+				Retries:      tt.retries,
+				RetryMaxWait: tt.retryMaxWait,
 			})
 
 			if tt.wantErr != "" {
@@ -55,6 +105,240 @@ func Test_newClient(t *testing.T) {
 				expectedAPIURL = "https://api.katapult.io"
 			}
 			assert.Equal(t, expectedAPIURL, apiURL)
+
+			// Check the retries, which default to 3 with a max wait of 30 seconds.
+			wantRetries, wantMaxWait := tt.wantRetries, tt.wantMaxWait
+			if tt.retries == "" {
+				wantRetries = 3
+			}
+			if tt.retryMaxWait == "" {
+				wantMaxWait = 30 * time.Second
+			}
+			rt := c.(*katapult.Client).HTTPClient.(*http.Client).Transport.(*retryTransport)
+			assert.Equal(t, wantRetries, rt.retries)
+			assert.Equal(t, wantMaxWait, rt.maxWait)
+		})
+	}
+}
+
+// Used to write a self signed client certificate and its key to PEM files.
+func writeClientCert(t *testing.T, dir string) (certPath, keyPath string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "build-agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath = filepath.Join(dir, "client.pem")
+	keyPath = filepath.Join(dir, "client-key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, ioutil.WriteFile(certPath, certPEM, 0o600))
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, ioutil.WriteFile(keyPath, keyPEM, 0o600))
+	return certPath, keyPath, cert
+}
+
+// Used to create a TLS proxy which needs a username and password. It tunnels CONNECT requests to the address
+// which was asked for, and records the addresses.
+func newTestProxy(t *testing.T) (srv *httptest.Server, tunnels *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	tunnels = &[]string{}
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := base64.StdEncoding.EncodeToString([]byte("agent:hunter2"))
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic "+auth {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer upstream.Close()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mu.Lock()
+		*tunnels = append(*tunnels, r.Host)
+		mu.Unlock()
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		_, _ = io.Copy(conn, upstream)
+	}))
+	return srv, tunnels
+}
+
+func Test_newClient_TLS(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath, clientCert := writeClientCert(t, dir)
+
+	// The API needs a client certificate. httptest uses the same certificate for every TLS server, so the CA
+	// bundle trusts both the API and the proxy.
+	api := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	api.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+		MinVersion: tls.VersionTLS12,
+	}
+	api.StartTLS()
+	defer api.Close()
+	caBundle := filepath.Join(dir, "ca.pem")
+	require.NoError(t, ioutil.WriteFile(caBundle,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw}), 0o600))
+	missingBundle := filepath.Join(dir, "missing.pem")
+	emptyBundle := filepath.Join(dir, "empty.pem")
+	require.NoError(t, ioutil.WriteFile(emptyBundle, []byte("not a certificate"), 0o600))
+
+	proxy, tunnels := newTestProxy(t)
+	defer proxy.Close()
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	proxyURL.User = url.UserPassword("agent", "hunter2")
+
+	tests := []struct {
+		name string
+
+		conf        config.Config
+		wantBody    string
+		wantReqErr  string
+		wantErr     string
+		wantTunnel  bool
+		wantWarning bool
+	}{
+		{
+			name:       "untrusted certificate",
+			conf:       config.Config{ClientCert: certPath, ClientKey: keyPath},
+			wantReqErr: "certificate signed by unknown authority",
+		},
+		{
+			name:       "no client certificate",
+			conf:       config.Config{CABundle: caBundle},
+			wantReqErr: "remote error: tls",
+		},
+		{
+			name:     "CA bundle and client certificate",
+			conf:     config.Config{CABundle: caBundle, ClientCert: certPath, ClientKey: keyPath},
+			wantBody: "hello build-agent",
+		},
+		{
+			name: "proxy",
+			conf: config.Config{
+				HTTPSProxy: proxyURL.String(), CABundle: caBundle, ClientCert: certPath, ClientKey: keyPath,
+			},
+			wantBody:   "hello build-agent",
+			wantTunnel: true,
+		},
+		{
+			name: "proxy without a password",
+			conf: config.Config{
+				HTTPSProxy: proxy.URL, CABundle: caBundle, ClientCert: certPath, ClientKey: keyPath,
+			},
+			wantReqErr: "Proxy Authentication Required",
+		},
+		{
+			name: "insecure skip verify",
+			conf: config.Config{
+				InsecureSkipVerify: "true", ClientCert: certPath, ClientKey: keyPath,
+			},
+			wantBody:    "hello build-agent",
+			wantWarning: true,
+		},
+		{
+			name:    "invalid proxy",
+			conf:    config.Config{HTTPSProxy: "proxy.test:3128"},
+			wantErr: "invalid HTTPS proxy: proxy.test:3128",
+		},
+		{
+			name:    "missing CA bundle",
+			conf:    config.Config{CABundle: missingBundle},
+			wantErr: "failed to read CA bundle: open " + missingBundle + ": no such file or directory",
+		},
+		{
+			name:    "empty CA bundle",
+			conf:    config.Config{CABundle: emptyBundle},
+			wantErr: "no certificates found in CA bundle " + emptyBundle,
+		},
+		{
+			name:    "client certificate without a key",
+			conf:    config.Config{ClientCert: certPath},
+			wantErr: "client_cert and client_key must be set together",
+		},
+		{
+			name: "invalid client certificate",
+			conf: config.Config{ClientCert: keyPath, ClientKey: keyPath},
+			wantErr: "invalid client certificate: tls: failed to find certificate PEM data in certificate input, " +
+				"but did find a private key; PEM inputs may have been switched",
+		},
+		{
+			name:    "invalid insecure skip verify",
+			conf:    config.Config{InsecureSkipVerify: "maybe"},
+			wantErr: "invalid insecure skip verify: maybe",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			warningOutput = buf
+			defer func() { warningOutput = os.Stderr }()
+			*tunnels = nil
+
+			conf := tt.conf
+			conf.APIURL = api.URL
+			conf.Retries = "0"
+			c, err := newClient(&conf)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			// The warning is only written once a request is sent.
+			assert.Empty(t, buf.String())
+			resp, err := c.(*katapult.Client).HTTPClient.(*http.Client).Get(api.URL)
+			if tt.wantReqErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantReqErr)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			b, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+			if tt.wantTunnel {
+				assert.Equal(t, []string{strings.TrimPrefix(api.URL, "https://")}, *tunnels)
+			} else {
+				assert.Empty(t, *tunnels)
+			}
+
+			// The warning is written the first time only.
+			resp, err = c.(*katapult.Client).HTTPClient.(*http.Client).Get(api.URL)
+			require.NoError(t, err)
+			resp.Body.Close()
+			if tt.wantWarning {
+				assert.Equal(t, 1, strings.Count(buf.String(), "WARNING: TLS certificate verification is turned off"))
+			} else {
+				assert.Empty(t, buf.String())
+			}
 		})
 	}
 }
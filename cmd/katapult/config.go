@@ -1,22 +1,333 @@
 package main
 
 import (
+	"errors"
+	"strings"
+
 	"github.com/krystal/katapult-cli/config"
 	"github.com/spf13/cobra"
 )
 
 const configFormat = `{{ Table (StringSlice "Key" "Value") (KVMap .) }}`
 
-func configCommand(conf *config.Config) *cobra.Command {
+// Defines the value which secrets are replaced with in the output.
+const redactedValue = "********"
+
+// Used to check if a config key holds a secret.
+func isSecretKey(key string) bool {
+	return strings.HasSuffix(key, "_token")
+}
+
+// Used to copy settings with any secrets replaced. Nested maps are also redacted.
+func redactSettings(settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			redacted[k] = redactSettings(x)
+		case string:
+			if x != "" && isSecretKey(k) {
+				x = redactedValue
+			}
+			redacted[k] = x
+		default:
+			redacted[k] = v
+		}
+	}
+	return redacted
+}
+
+// Defines the result of changing a config key.
+type configChange struct {
+	Key     string `json:"key" yaml:"key"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty"`
+	Profile string `json:"profile" yaml:"profile"`
+	File    string `json:"file" yaml:"file"`
+}
+
+// Used to get the help text listing the config keys.
+func configKeysHelp() string {
+	return "The key must be one of: " + strings.Join(config.Keys(), ", ") + "."
+}
+
+func configGetCmd(conf *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "get",
+		Args:  cobra.ExactArgs(1),
+		Short: "Get a configuration value",
+		Long: "Get a configuration value after flags, environment variables and the active profile are applied. " +
+			configKeysHelp(),
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			value, err := conf.Get(args[0])
+			if err != nil {
+				return nil, err
+			}
+			if showSecrets, _ := cmd.Flags().GetBool("show-secrets"); !showSecrets && value != "" &&
+				isSecretKey(args[0]) {
+				value = redactedValue
+			}
+			return &genericOutput{
+				item:                value,
+				defaultTextTemplate: "{{ . }}\n",
+			}, nil
+		}),
+	}
+	cmd.Flags().Bool("show-secrets", false, "Show the value even if it is a secret.")
+	return cmd
+}
+
+func configSetCmd(conf *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set",
+		Args:  cobra.ExactArgs(2),
+		Short: "Set a configuration value",
+		Long:  "Set a configuration value in the active profile. " + configKeysHelp(),
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			// The token is saved with auth login so that it goes to the credential store, rather than being written
+			// to the config file where the token from the store would take priority over it.
+			if args[0] == "api_token" {
+				return nil, errors.New("api_token can't be set with config set, use auth login instead")
+			}
+			if err := conf.SetValue(args[0], args[1]); err != nil {
+				return nil, err
+			}
+			if err := conf.WriteConfig(); err != nil {
+				return nil, err
+			}
+			change := &configChange{
+				Key: args[0], Value: args[1], Profile: conf.ProfileName(), File: conf.ConfigFileUsed(),
+			}
+			return &genericOutput{
+				item:                change,
+				defaultTextTemplate: "Set {{ .Key }} in the {{ .Profile }} profile in {{ .File }}.\n",
+			}, nil
+		}),
+	}
+}
+
+func configUnsetCmd(conf *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset",
+		Args:  cobra.ExactArgs(1),
+		Short: "Remove a configuration value",
+		Long:  "Remove a configuration value from the active profile. " + configKeysHelp(),
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			if err := conf.UnsetValue(args[0]); err != nil {
+				return nil, err
+			}
+			if err := conf.WriteConfig(); err != nil {
+				return nil, err
+			}
+			change := &configChange{Key: args[0], Profile: conf.ProfileName(), File: conf.ConfigFileUsed()}
+			return &genericOutput{
+				item:                change,
+				defaultTextTemplate: "Removed {{ .Key }} from the {{ .Profile }} profile in {{ .File }}.\n",
+			}, nil
+		}),
+	}
+}
+
+// Defines a profile in the output of list-profiles.
+type profileListItem struct {
+	Name         string `json:"name" yaml:"name"`
+	APIURL       string `json:"api_url" yaml:"api_url"`
+	Organization string `json:"organization" yaml:"organization"`
+	Active       bool   `json:"active" yaml:"active"`
+}
+
+// Defines the columns of config list-profiles.
+var profilesListColumns = []*outputColumn{
+	{Header: "Name", Path: "Name"},
+	{Header: "API URL", Path: "APIURL"},
+	{Header: "Organization", Path: "Organization"},
+	{Header: "Active", Path: "Active"},
+}
+
+func configListProfilesCmd(conf *config.Config) *cobra.Command {
 	return &cobra.Command{
+		Use:     "list-profiles",
+		Aliases: []string{"profiles"},
+		Short:   "List the configured profiles",
+		Long: "List the configured profiles. The default profile is made up of the settings at the top level of " +
+			"the config file.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			active := conf.ProfileName()
+			names := conf.ProfileNames()
+			items := make([]*profileListItem, len(names))
+			for i, name := range names {
+				p, _ := conf.GetProfile(name)
+				items[i] = &profileListItem{
+					Name:         name,
+					APIURL:       p.APIURL,
+					Organization: p.Organization,
+					Active:       name == active,
+				}
+			}
+			return &genericOutput{
+				item:    items,
+				columns: profilesListColumns,
+			}, nil
+		}),
+	}
+}
+
+func configUseProfileCmd(conf *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "use-profile",
+		Args:  cobra.ExactArgs(1),
+		Short: "Set the profile to use",
+		Long: "Set the profile to use when one isn't set with --profile or KATAPULT_PROFILE. The argument should be " +
+			"the name of the profile. Use \"" + config.DefaultProfile + "\" to go back to the top level settings.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			if create, _ := cmd.Flags().GetBool("create"); create {
+				if err := conf.CreateProfile(args[0]); err != nil {
+					return nil, err
+				}
+			}
+			if err := conf.UseProfile(args[0]); err != nil {
+				return nil, err
+			}
+			if err := conf.WriteConfig(); err != nil {
+				return nil, err
+			}
+			return &genericOutput{
+				item:                map[string]string{"profile": args[0]},
+				defaultTextTemplate: "Now using the profile {{ .profile }}.\n",
+			}, nil
+		}),
+	}
+	cmd.Flags().Bool("create", false, "Create the profile if it doesn't exist.")
+	return cmd
+}
+
+// Defines an environment variable which the CLI supports.
+type envVar struct {
+	Name        string
+	Description string
+	Secret      bool
+}
+
+// Defines every environment variable which the CLI supports.
+var supportedEnvVars = []*envVar{
+	{Name: "KATAPULT_API_URL", Description: "The URL of the Katapult API."},
+	{Name: "KATAPULT_API_TOKEN", Description: "The API token.", Secret: true},
+	{Name: "KATAPULT_ORGANIZATION", Description: "The default organization ID or subdomain."},
+	{Name: "KATAPULT_CREDENTIAL_STORE", Description: "The credential store used for the API token."},
+	{Name: "KATAPULT_CREDENTIAL_HELPER", Description: "The credential helper command."},
+	{Name: "KATAPULT_CREDENTIALS_PASSPHRASE", Description: "The passphrase of the credentials file.", Secret: true},
+	{Name: "KATAPULT_RETRIES", Description: "The number of times to retry rate limited or failed requests."},
+	{Name: "KATAPULT_RETRY_MAX_WAIT", Description: "The longest time to wait between retries."},
+	{Name: "KATAPULT_HTTPS_PROXY", Description: "The URL of the proxy to send API requests through."},
+	{Name: "KATAPULT_CA_BUNDLE", Description: "The path of a PEM file with extra certificate authorities to trust."},
+	{Name: "KATAPULT_CLIENT_CERT", Description: "The path of a PEM client certificate."},
+	{Name: "KATAPULT_CLIENT_KEY", Description: "The path of the PEM key of the client certificate."},
+	{Name: "KATAPULT_INSECURE_SKIP_VERIFY", Description: "Turns off checking the certificate of the API."},
+	{Name: "KATAPULT_PROFILE", Description: "The config profile to use."},
+	{Name: config.ConfigEnv, Description: "The path of the config file."},
+	{Name: "KATAPULT_OUTPUT", Description: "The default output type."},
+	{Name: "KATAPULT_DEBUG", Description: "Traces API requests, set to true or body."},
+	{Name: "KATAPULT_LOG_FORMAT", Description: "The format of the debug trace."},
+	{Name: "KATAPULT_RECORD", Description: "The file to record API requests to."},
+	{Name: "KATAPULT_REPLAY", Description: "The file to replay API requests from."},
+	{Name: "KATAPULT_ORG_SUBDOMAIN", Description: "The organization subdomain for vm create."},
+	{Name: "KATAPULT_ORG_NAME", Description: "The organization name for vm create."},
+	{Name: "KATAPULT_DC_ID", Description: "The data center ID for vm create."},
+	{Name: "KATAPULT_DC_NAME", Description: "The data center name for vm create."},
+	{Name: "KATAPULT_PACKAGE_ID", Description: "The package ID for vm create."},
+	{Name: "KATAPULT_PACKAGE_NAME", Description: "The package name for vm create."},
+	{Name: "KATAPULT_DISTRIBUTION_ID", Description: "The disk template ID for vm create."},
+	{Name: "KATAPULT_DISTRIBUTION_NAME", Description: "The disk template name for vm create."},
+	{Name: "KATAPULT_IP_ADDRESSES", Description: "Comma separated IP addresses for vm create."},
+	{Name: "KATAPULT_SSH_KEY_IDS", Description: "Comma separated SSH key IDs for vm create."},
+	{Name: "KATAPULT_SSH_KEY_NAMES", Description: "Comma separated SSH key names for vm create."},
+	{Name: "KATAPULT_SSH_KEY_FINGERPRINTS", Description: "Comma separated SSH key fingerprints for vm create."},
+	{Name: "KATAPULT_TAG_IDS", Description: "Comma separated tag IDs for vm create."},
+	{Name: "KATAPULT_TAG_NAMES", Description: "Comma separated tag names for vm create."},
+	{Name: "KATAPULT_NAME", Description: "The virtual machine name for vm create."},
+	{Name: "KATAPULT_HOSTNAME", Description: "The virtual machine hostname for vm create."},
+	{Name: "KATAPULT_DESCRIPTION", Description: "The virtual machine description for vm create."},
+}
+
+// Defines an environment variable in the output of config env.
+type envVarItem struct {
+	Name        string `json:"name" yaml:"name"`
+	Set         bool   `json:"set" yaml:"set"`
+	Value       string `json:"value,omitempty" yaml:"value,omitempty"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// Defines the columns of config env.
+var envVarsColumns = []*outputColumn{
+	{Header: "Name", Path: "Name"},
+	{Header: "Set", Path: "Set"},
+	{Header: "Value", Path: "Value"},
+	{Header: "Description", Path: "Description"},
+}
+
+func configEnvCmd(envs envGetter) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "env",
+		Args:  cobra.NoArgs,
+		Short: "List the supported environment variables",
+		Long: "List every environment variable which the CLI supports and whether it is set. Secrets are redacted " +
+			"unless --show-secrets is set.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+			items := make([]*envVarItem, len(supportedEnvVars))
+			for i, v := range supportedEnvVars {
+				value := envs.Get(v.Name)
+				if value != "" && v.Secret && !showSecrets {
+					value = redactedValue
+				}
+				items[i] = &envVarItem{Name: v.Name, Set: value != "", Value: value, Description: v.Description}
+			}
+			return &genericOutput{
+				item:    items,
+				columns: envVarsColumns,
+			}, nil
+		}),
+	}
+	cmd.Flags().Bool("show-secrets", false, "Show the values of secrets.")
+	return cmd
+}
+
+// Used to check if a command is the config command or one of its subcommands.
+func isConfigCommand(cmd *cobra.Command) bool {
+	for ; cmd.HasParent(); cmd = cmd.Parent() {
+		if cmd.Name() == "config" && !cmd.Parent().HasParent() {
+			return true
+		}
+	}
+	return false
+}
+
+func configCommand(conf *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
 		Use:   "config",
 		Short: "Print configuration",
-		Long:  "Print parsed configuration in YAML/JSON format.",
+		Long: "Print parsed configuration in YAML/JSON format. Secrets are redacted unless --show-secrets is set. " +
+			"The subcommands can be used to change the configuration.",
 		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			settings := conf.AllSettings()
+			if showSecrets, _ := cmd.Flags().GetBool("show-secrets"); !showSecrets {
+				settings = redactSettings(settings)
+			}
 			return &genericOutput{
-				item:                conf.AllSettings(),
+				item:                settings,
 				defaultTextTemplate: configFormat,
 			}, nil
 		}),
 	}
+	cmd.Flags().Bool("show-secrets", false, "Show secrets such as API tokens.")
+
+	cmd.AddCommand(
+		configGetCmd(conf),
+		configSetCmd(conf),
+		configUnsetCmd(conf),
+		configListProfilesCmd(conf),
+		configUseProfileCmd(conf),
+		configEnvCmd(osGetter{}),
+	)
+
+	return cmd
 }
@@ -1,16 +1,21 @@
 package main
 
 import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/krystal/katapult-cli/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig(t *testing.T) {
 	tests := []struct {
 		name string
 
+		args     []string
 		apiToken string
 		apiURL   string
 		output   string
@@ -47,6 +52,12 @@ func TestConfig(t *testing.T) {
 			apiURL:   "testURL",
 			output:   "yaml",
 		},
+		{
+			name:     "show secrets",
+			args:     []string{"--show-secrets"},
+			apiToken: "testKey",
+			apiURL:   "testURL",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -55,6 +66,318 @@ func TestConfig(t *testing.T) {
 			conf.SetDefault("api_token", tt.apiToken)
 			conf.SetDefault("api_url", tt.apiURL)
 			cmd := configCommand(conf)
+			cmd.SetArgs(tt.args)
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, "", "")
+			outputFlag = ""
+		})
+	}
+}
+
+// Defines a config file with profiles.
+const profilesConfig = `api_url: https://api.katapult.io
+api_token: top-level-token
+organization: loge
+current_profile: staging
+profiles:
+  staging:
+    api_url: https://staging.katapult.io
+    api_token: staging-token
+  other:
+    api_token: other-token
+    organization: org_1
+`
+
+// Used to load a config from a temporary file with the content specified.
+func loadTestConfig(t *testing.T, content string) (*config.Config, string) {
+	t.Helper()
+	fp := filepath.Join(t.TempDir(), "katapult.yaml")
+	require.NoError(t, ioutil.WriteFile(fp, []byte(content), 0o600))
+	conf, err := config.New()
+	require.NoError(t, err)
+	conf.SetConfigFile(fp)
+	require.NoError(t, conf.Load())
+	return conf, fp
+}
+
+func TestConfig_ListProfiles(t *testing.T) {
+	tests := []struct {
+		name string
+
+		content string
+		output  string
+	}{
+		{
+			name:    "no profiles",
+			content: "api_url: https://api.katapult.io\n",
+		},
+		{
+			name:    "profiles",
+			content: profilesConfig,
+		},
+		{
+			name:    "profiles json",
+			content: profilesConfig,
+			output:  "json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, _ := loadTestConfig(t, tt.content)
+			cmd := configCommand(conf)
+			cmd.SetArgs([]string{"list-profiles"})
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, "", "")
+			outputFlag = ""
+		})
+	}
+}
+
+func TestConfig_UseProfile(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		current string
+		wantErr string
+	}{
+		{
+			name:    "existing profile",
+			args:    []string{"use-profile", "other"},
+			current: "other",
+		},
+		{
+			name: "default profile",
+			args: []string{"use-profile", "default"},
+		},
+		{
+			name:    "unknown profile",
+			args:    []string{"use-profile", "unknown"},
+			wantErr: "the profile unknown does not exist",
+		},
+		{
+			name:    "create profile",
+			args:    []string{"use-profile", "new", "--create"},
+			current: "new",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, fp := loadTestConfig(t, profilesConfig)
+			cmd := configCommand(conf)
+			cmd.SetArgs(tt.args)
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			if tt.wantErr != "" {
+				return
+			}
+
+			// Check the profile was written.
+			written, err := config.New()
+			require.NoError(t, err)
+			written.SetConfigFile(fp)
+			require.NoError(t, written.Load())
+			assert.Equal(t, tt.current, written.CurrentProfile)
+		})
+	}
+}
+
+func TestConfig_Get(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		wantErr string
+	}{
+		{
+			name: "profile value",
+			args: []string{"get", "api_url"},
+		},
+		{
+			name: "top level value",
+			args: []string{"get", "organization"},
+		},
+		{
+			name: "redacted token",
+			args: []string{"get", "api_token"},
+		},
+		{
+			name: "shown token",
+			args: []string{"get", "api_token", "--show-secrets"},
+		},
+		{
+			name: "unknown key",
+			args: []string{"get", "api_key"},
+			wantErr: "unknown config key api_key, must be one of: api_url, api_token, organization, " +
+				"credential_store, credential_helper, retries, retry_max_wait, https_proxy, ca_bundle, client_cert, " +
+				"client_key, insecure_skip_verify",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, _ := loadTestConfig(t, profilesConfig)
+			cmd := configCommand(conf)
+			cmd.SetArgs(tt.args)
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+		})
+	}
+}
+
+func TestConfig_SetUnset(t *testing.T) {
+	tests := []struct {
+		name string
+
+		content string
+		args    []string
+		stdout  string
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "set in default profile",
+			content: "api_token: abc\n",
+			args:    []string{"set", "organization", "loge"},
+			stdout:  "Set organization in the default profile in <file>.\n",
+			want:    "api_token: abc\norganization: loge\n",
+		},
+		{
+			name:    "set in active profile",
+			content: profilesConfig,
+			args:    []string{"set", "organization", "org_2"},
+			stdout:  "Set organization in the staging profile in <file>.\n",
+			want: strings.Replace(profilesConfig, "    api_token: staging-token\n",
+				"    api_token: staging-token\n    organization: org_2\n", 1),
+		},
+		{
+			name:    "set api token",
+			content: profilesConfig,
+			args:    []string{"set", "api_token", "new-token"},
+			wantErr: "api_token can't be set with config set, use auth login instead",
+		},
+		{
+			name:    "set unknown key",
+			content: profilesConfig,
+			args:    []string{"set", "token", "abc"},
+			wantErr: "unknown config key token, must be one of: api_url, api_token, organization, credential_store, " +
+				"credential_helper, retries, retry_max_wait, https_proxy, ca_bundle, client_cert, client_key, " +
+				"insecure_skip_verify",
+		},
+		{
+			name:    "set invalid URL",
+			content: profilesConfig,
+			args:    []string{"set", "https_proxy", "proxy:3128"},
+			wantErr: "invalid value proxy:3128 for https_proxy, must be a URL such as https://example.com",
+		},
+		{
+			name:    "set invalid retries",
+			content: profilesConfig,
+			args:    []string{"set", "retries", "many"},
+			wantErr: "invalid value many for retries, must be a number which is 0 or more",
+		},
+		{
+			name:    "set invalid retry max wait",
+			content: profilesConfig,
+			args:    []string{"set", "retry_max_wait", "30"},
+			wantErr: "invalid value 30 for retry_max_wait, must be a duration such as 30s",
+		},
+		{
+			name:    "set invalid insecure skip verify",
+			content: profilesConfig,
+			args:    []string{"set", "insecure_skip_verify", "yes"},
+			wantErr: "invalid value yes for insecure_skip_verify, must be true or false",
+		},
+		{
+			name:    "set unknown credential store",
+			content: profilesConfig,
+			args:    []string{"set", "credential_store", "vault"},
+			wantErr: "invalid value vault for credential_store, must be one of: plaintext, helper, keyring, file",
+		},
+		{
+			name:    "set valid retry max wait",
+			content: "api_token: abc\n",
+			args:    []string{"set", "retry_max_wait", "1m"},
+			stdout:  "Set retry_max_wait in the default profile in <file>.\n",
+			want:    "api_token: abc\nretry_max_wait: 1m\n",
+		},
+		{
+			name:    "unset in default profile",
+			content: "api_token: abc\norganization: loge\n",
+			args:    []string{"unset", "organization"},
+			stdout:  "Removed organization from the default profile in <file>.\n",
+			want:    "api_token: abc\n",
+		},
+		{
+			name:    "unset in active profile",
+			content: profilesConfig,
+			args:    []string{"unset", "api_url"},
+			stdout:  "Removed api_url from the staging profile in <file>.\n",
+			want:    strings.Replace(profilesConfig, "    api_url: https://staging.katapult.io\n", "", 1),
+		},
+		{
+			name:    "unset unknown key",
+			content: profilesConfig,
+			args:    []string{"unset", "token"},
+			wantErr: "unknown config key token, must be one of: api_url, api_token, organization, credential_store, " +
+				"credential_helper, retries, retry_max_wait, https_proxy, ca_bundle, client_cert, client_key, " +
+				"insecure_skip_verify",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf, fp := loadTestConfig(t, tt.content)
+			cmd := configCommand(conf)
+			cmd.SetArgs(tt.args)
+			stdout := assertCobraCommandReturnStdout(t, cmd, tt.wantErr, "")
+			if tt.wantErr != "" {
+				return
+			}
+			assert.Equal(t, tt.stdout, strings.ReplaceAll(stdout, fp, "<file>"))
+
+			// Load the written config and the expected config and compare them.
+			written, _ := loadTestConfig(t, readFile(t, fp))
+			want, _ := loadTestConfig(t, tt.want)
+			assert.Equal(t, want.AllSettings(), written.AllSettings())
+		})
+	}
+}
+
+// Used to read a file as a string.
+func readFile(t *testing.T, fp string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(fp)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestConfig_Env(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args   []string
+		envs   map[string]string
+		output string
+	}{
+		{
+			name: "nothing set",
+		},
+		{
+			name: "secrets redacted",
+			envs: map[string]string{"KATAPULT_API_TOKEN": "abc", "KATAPULT_PROFILE": "staging"},
+		},
+		{
+			name: "show secrets",
+			args: []string{"--show-secrets"},
+			envs: map[string]string{"KATAPULT_API_TOKEN": "abc", "KATAPULT_PROFILE": "staging"},
+		},
+		{
+			name:   "json output",
+			envs:   map[string]string{"KATAPULT_OUTPUT": "json"},
+			output: "json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := configEnvCmd(mapGetter{m: tt.envs})
+			cmd.SetArgs(tt.args)
 			outputFlag = tt.output
 			assertCobraCommand(t, cmd, "", "")
 			outputFlag = ""
@@ -2,6 +2,7 @@ package console
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"sync"
 	"sync/atomic"
@@ -75,3 +76,30 @@ func newChunkReader(r io.Reader) *chunkReader {
 	go c.loop()
 	return &c
 }
+
+// Used to read from a reader, giving up if the context is done first. A read can't be stopped, so it carries on in
+// the background and anything it reads is thrown away.
+func readContext(ctx context.Context, r io.Reader, buf []byte) (int, error) {
+	if ctx.Done() == nil {
+		// The context can't be cancelled, so there is no need for a goroutine.
+		return r.Read(buf)
+	}
+
+	type result struct {
+		n   int
+		err error
+	}
+	ch := make(chan result, 1)
+	a := make([]byte, len(buf))
+	go func() {
+		n, err := r.Read(a)
+		ch <- result{n: n, err: err}
+	}()
+	select {
+	case res := <-ch:
+		copy(buf, a[:res.n])
+		return res.n, res.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
@@ -102,7 +102,9 @@ func (g *gotermTerminal) SignalInterrupt() {
 		g.raw = nil
 	}
 	g.m.Unlock()
-	os.Exit(1)
+
+	// Exit with the status code used by shells for Ctrl-C.
+	os.Exit(130)
 }
 
 func (g *gotermTerminal) MakeRaw() error {
@@ -1,6 +1,7 @@
 package console
 
 import (
+	"context"
 	"io"
 	"math"
 	"strings"
@@ -334,9 +335,12 @@ func chunkForConsole(renderedFields [][]string, usableItemRows int) []consoleChu
 	return consoleSizedChunks
 }
 
-// MultiInput is used to format multiple input slots to the display.
+// MultiInput is used to format multiple input slots to the display. The context error is returned if the context
+// is done before the fields are submitted.
 //nolint:funlen
-func MultiInput(fields []InputField, stdin io.Reader, terminal TerminalInterface) []string {
+func MultiInput(
+	ctx context.Context, fields []InputField, stdin io.Reader, terminal TerminalInterface,
+) ([]string, error) {
 	// Ensure the terminal isn't nil.
 	if terminal == nil {
 		terminal = &gotermTerminal{}
@@ -369,9 +373,9 @@ func MultiInput(fields []InputField, stdin io.Reader, terminal TerminalInterface
 		// Get the usable item rows.
 		usableItemRows := terminal.Height() - 1
 		if 0 >= usableItemRows {
-			// Weird. Return status code 1.
+			// Weird. Give up as if we were interrupted.
 			terminal.SignalInterrupt()
-			return nil
+			return nil, nil
 		}
 
 		// Get the width.
@@ -425,7 +429,12 @@ func MultiInput(fields []InputField, stdin io.Reader, terminal TerminalInterface
 			for {
 				// The 15ms cooldown is here to give the terminal time to catch up.
 				// For some reason, whilst Ubuntu's terminal seems fine without this, some (e.g.: iterm, goland) fail.
-				time.Sleep(time.Millisecond * 15)
+				select {
+				case <-ctx.Done():
+					_ = terminal.Unraw()
+					return nil, ctx.Err()
+				case <-time.After(time.Millisecond * 15):
+				}
 
 				// Flush the buffer and check what we have.
 				a := r.flush()
@@ -436,7 +445,7 @@ func MultiInput(fields []InputField, stdin io.Reader, terminal TerminalInterface
 							v.a, v.n, activeIndex, fields, highlightedIndexes, fieldsContent, terminal)
 						if ret {
 							_ = terminal.Unraw()
-							return fieldsContent
+							return fieldsContent, nil
 						}
 					}
 					break
@@ -444,15 +453,16 @@ func MultiInput(fields []InputField, stdin io.Reader, terminal TerminalInterface
 			}
 		} else {
 			buf := make([]byte, 3)
-			n, err := stdin.Read(buf)
+			n, err := readContext(ctx, stdin, buf)
 			if err != nil {
-				return nil
+				_ = terminal.Unraw()
+				return nil, ctx.Err()
 			}
 			var ret bool
 			activeIndex, ret = handleKeypress(buf, n, activeIndex, fields, highlightedIndexes, fieldsContent, terminal)
 			if ret {
 				_ = terminal.Unraw()
-				return fieldsContent
+				return fieldsContent, nil
 			}
 		}
 	}
@@ -1,6 +1,8 @@
 package console
 
 import (
+	"context"
+	"io"
 	"strings"
 	"testing"
 
@@ -509,7 +511,8 @@ func TestMultiInput(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			stdin := &StdinDripFeeder{T: t, Inputs: tt.inputs}
 			stdout := &MockTerminal{CustomWidth: 50}
-			res := MultiInput(tt.fields, stdin, stdout)
+			res, err := MultiInput(context.Background(), tt.fields, stdin, stdout)
+			assert.NoError(t, err)
 			if tt.shouldExit {
 				assert.Equal(t, tt.shouldExit, stdout.ExitSignaled)
 			} else {
@@ -522,3 +525,14 @@ func TestMultiInput(t *testing.T) {
 		})
 	}
 }
+
+func TestMultiInput_Cancel(t *testing.T) {
+	// The reader never returns, so the input can only stop because the context is cancelled.
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := MultiInput(ctx, []InputField{{Name: "test"}}, r, &MockTerminal{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, res)
+}
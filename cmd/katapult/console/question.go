@@ -1,6 +1,7 @@
 package console
 
 import (
+	"bufio"
 	"io"
 	"strings"
 
@@ -26,3 +27,19 @@ func Question(question string, blankAcceptable bool, bufferedStdin stringReader,
 		}
 	}
 }
+
+// Confirm is used to ask the user a yes/no question. Anything other than "y" or "yes" (or the end of stdin) is
+// treated as a no.
+func Confirm(question string, stdin io.Reader, stdout io.Writer) bool {
+	// Print the question.
+	_, _ = stdout.Write([]byte(goterm.Color(question+" [y/N]", goterm.CYAN) + " "))
+
+	// Read the first line of stdin.
+	text, _ := bufio.NewReader(stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(text)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
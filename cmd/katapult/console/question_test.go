@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/krystal/katapult-cli/internal/golden"
@@ -56,3 +57,41 @@ func TestQuestion(t *testing.T) {
 		})
 	}
 }
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		name string
+
+		stdin string
+		want  bool
+	}{
+		{
+			name:  "yes",
+			stdin: "yes\n",
+			want:  true,
+		},
+		{
+			name:  "y with whitespace",
+			stdin: " Y \r\n",
+			want:  true,
+		},
+		{
+			name:  "no",
+			stdin: "n\n",
+		},
+		{
+			name:  "blank",
+			stdin: "\n",
+		},
+		{
+			name: "end of stdin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			assert.Equal(t, tt.want, Confirm("test", strings.NewReader(tt.stdin), stdout))
+			assert.Equal(t, "\x1b[36mtest [y/N]\x1b[0m ", stdout.String())
+		})
+	}
+}
@@ -2,6 +2,7 @@ package console
 
 import (
 	"container/list"
+	"context"
 	"io"
 	"math"
 	"strings"
@@ -305,9 +306,10 @@ func renderRowItem(hasColumn bool, highlightIndex, i, width int, v interface{},
 	}
 }
 
-// items is either []string or [][]string (if columns isn't nil).
+// items is either []string or [][]string (if columns isn't nil). The context error is returned if the context is
+// done before a selection is made.
 //nolint:funlen,lll
-func selectorComponent(question string, columns []string, items interface{}, stdin io.Reader, multiple bool, terminal TerminalInterface) interface{} {
+func selectorComponent(ctx context.Context, question string, columns []string, items interface{}, stdin io.Reader, multiple bool, terminal TerminalInterface) (interface{}, error) {
 	// Pre-initialize things we need below.
 	query := ""
 	buf := make([]byte, 3)
@@ -323,9 +325,9 @@ func selectorComponent(question string, columns []string, items interface{}, std
 		// Get the usable item rows.
 		usableItemRows := terminal.Height() - 1
 		if 0 >= usableItemRows {
-			// Weird. Return status code 1.
+			// Weird. Give up as if we were interrupted.
 			terminal.SignalInterrupt()
-			return nil
+			return nil, nil
 		}
 
 		// Get the width.
@@ -417,45 +419,68 @@ func selectorComponent(question string, columns []string, items interface{}, std
 		if err != nil {
 			panic(err)
 		}
-		n, _ := stdin.Read(buf)
+		n, _ := readContext(ctx, stdin, buf)
 		_ = terminal.Unraw()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if x := handleInput(buf, multiple, matchedLen,
 			n, &highlightIndex, columns != nil, selectedItems, &query, matched, terminal); x != nil {
-			return x
+			return x, nil
 		}
 	}
 }
 
 // FuzzySelector is used to create a selector. If terminal is nil, we will default to goterm.
-func FuzzySelector(question string, items []string, stdin io.Reader, terminal TerminalInterface) string {
+func FuzzySelector(
+	ctx context.Context, question string, items []string, stdin io.Reader, terminal TerminalInterface,
+) (string, error) {
 	if terminal == nil {
 		terminal = &gotermTerminal{}
 	}
-	return selectorComponent(question, nil, items, stdin, false, terminal).([]string)[0]
+	x, err := selectorComponent(ctx, question, nil, items, stdin, false, terminal)
+	if err != nil {
+		return "", err
+	}
+	return x.([]string)[0], nil
 }
 
 // FuzzyMultiSelector is used to create a selector with multiple items.
-func FuzzyMultiSelector(question string, items []string, stdin io.Reader, terminal TerminalInterface) []string {
+func FuzzyMultiSelector(
+	ctx context.Context, question string, items []string, stdin io.Reader, terminal TerminalInterface,
+) ([]string, error) {
 	if terminal == nil {
 		terminal = &gotermTerminal{}
 	}
-	return selectorComponent(question, nil, items, stdin, true, terminal).([]string)
+	x, err := selectorComponent(ctx, question, nil, items, stdin, true, terminal)
+	if err != nil {
+		return nil, err
+	}
+	return x.([]string), nil
 }
 
 // FuzzyTableSelector is used to create a selector with a table.
-func FuzzyTableSelector(question string, columns []string, items [][]string,
-	stdin io.Reader, terminal TerminalInterface) []string {
+func FuzzyTableSelector(ctx context.Context, question string, columns []string, items [][]string,
+	stdin io.Reader, terminal TerminalInterface) ([]string, error) {
 	if terminal == nil {
 		terminal = &gotermTerminal{}
 	}
-	return selectorComponent(question, columns, items, stdin, false, terminal).([][]string)[0]
+	x, err := selectorComponent(ctx, question, columns, items, stdin, false, terminal)
+	if err != nil {
+		return nil, err
+	}
+	return x.([][]string)[0], nil
 }
 
 // FuzzyTableMultiSelector is used to create a selector with a table and multiple items.
-func FuzzyTableMultiSelector(question string, columns []string, items [][]string,
-	stdin io.Reader, terminal TerminalInterface) [][]string {
+func FuzzyTableMultiSelector(ctx context.Context, question string, columns []string, items [][]string,
+	stdin io.Reader, terminal TerminalInterface) ([][]string, error) {
 	if terminal == nil {
 		terminal = &gotermTerminal{}
 	}
-	return selectorComponent(question, columns, items, stdin, true, terminal).([][]string)
+	x, err := selectorComponent(ctx, question, columns, items, stdin, true, terminal)
+	if err != nil {
+		return nil, err
+	}
+	return x.([][]string), nil
 }
@@ -1,6 +1,8 @@
 package console
 
 import (
+	"context"
+	"io"
 	"testing"
 
 	"github.com/krystal/katapult-cli/internal/golden"
@@ -336,7 +338,9 @@ func TestSelector(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			stdin := &StdinDripFeeder{T: t, Inputs: tt.inputs}
 			stdout := &MockTerminal{}
-			res := selectorComponent("test", tt.columns, tt.items, stdin, tt.multiple, stdout)
+			res, err := selectorComponent(
+				context.Background(), "test", tt.columns, tt.items, stdin, tt.multiple, stdout)
+			assert.NoError(t, err)
 			if tt.shouldExit {
 				assert.Equal(t, tt.shouldExit, stdout.ExitSignaled)
 			} else {
@@ -350,3 +354,17 @@ func TestSelector(t *testing.T) {
 		})
 	}
 }
+
+func TestSelector_Cancel(t *testing.T) {
+	// The reader never returns, so the selector can only stop because the context is cancelled.
+	r, w := io.Pipe()
+	defer w.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := selectorComponent(ctx, "test", nil, []string{"hello", "world"}, r, false, &MockTerminal{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, res)
+
+	_, err = FuzzySelector(ctx, "test", []string{"hello"}, r, &MockTerminal{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
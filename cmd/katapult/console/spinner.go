@@ -0,0 +1,88 @@
+package console
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/buger/goterm"
+)
+
+// Defines the frames of the spinner.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Defines the interval between spinner frames.
+const spinnerInterval = 100 * time.Millisecond
+
+// Spinner is used to display a spinner with a message on a single line while something is happening.
+type Spinner struct {
+	w       io.Writer
+	message string
+	frame   int
+	stop    chan struct{}
+	done    chan struct{}
+	m       sync.Mutex
+}
+
+// NewSpinner is used to create a spinner which writes to the writer specified.
+func NewSpinner(w io.Writer, message string) *Spinner {
+	return &Spinner{w: w, message: message}
+}
+
+// Used to render the current frame. Must be called with the lock held.
+func (s *Spinner) render() {
+	_, _ = fmt.Fprintf(s.w, "\r\033[K%s %s", goterm.Color(spinnerFrames[s.frame], goterm.CYAN), s.message)
+}
+
+// Start is used to start rendering the spinner.
+func (s *Spinner) Start() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.render()
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.m.Lock()
+				s.frame = (s.frame + 1) % len(spinnerFrames)
+				s.render()
+				s.m.Unlock()
+			}
+		}
+	}(s.stop, s.done)
+}
+
+// SetMessage is used to change the message displayed next to the spinner.
+func (s *Spinner) SetMessage(message string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.message = message
+	if s.stop != nil {
+		s.render()
+	}
+}
+
+// Stop is used to stop the spinner and clear the line it was rendered on.
+func (s *Spinner) Stop() {
+	s.m.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.m.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+	_, _ = fmt.Fprint(s.w, "\r\033[K")
+}
@@ -0,0 +1,25 @@
+package console
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpinner(t *testing.T) {
+	buf := &bytes.Buffer{}
+	s := NewSpinner(buf, "Waiting")
+	s.Start()
+	s.SetMessage("Still waiting")
+	s.Stop()
+
+	// Stopping twice should be a no-op.
+	s.Stop()
+
+	assert.Equal(t,
+		"\r\x1b[K\x1b[36m⠋\x1b[0m Waiting"+
+			"\r\x1b[K\x1b[36m⠋\x1b[0m Still waiting"+
+			"\r\x1b[K",
+		buf.String())
+}
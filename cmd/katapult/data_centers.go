@@ -15,8 +15,21 @@ type dataCentersClient interface {
 	Get(ctx context.Context, ref core.DataCenterRef) (*core.DataCenter, *katapult.Response, error)
 }
 
-//nolint:lll
-const dataCentersFormat = `{{ Table (StringSlice "Name" "Permalink" "Country Name") (MultipleRows . "Name" "Permalink" "Country.Name") }}`
+// Defines the columns of dc list.
+var dataCentersColumns = []*outputColumn{
+	{Header: "Name", Path: "Name"},
+	{Header: "Permalink", Path: "Permalink"},
+	{Header: "Country Name", Path: "Country.Name"},
+}
+
+// Defines the columns of dc list -o wide.
+var dataCentersWideColumns = []*outputColumn{
+	{Header: "ID", Path: "ID"},
+	{Header: "Name", Path: "Name"},
+	{Header: "Permalink", Path: "Permalink"},
+	{Header: "Country Name", Path: "Country.Name"},
+	{Header: "Country Code", Path: "Country.ISOCode2"},
+}
 
 //nolint:lll
 const getDataCenterFormat = `{{ Table (StringSlice "Name" "Permalink" "Country Name") (SingleRow .Name .Permalink .Country.Name) }}`
@@ -34,8 +47,9 @@ func listDataCentersCmd(client dataCentersClient) *cobra.Command {
 			}
 
 			return &genericOutput{
-				item:                dcs,
-				defaultTextTemplate: dataCentersFormat,
+				item:        dcs,
+				columns:     dataCentersColumns,
+				wideColumns: dataCentersWideColumns,
 			}, nil
 		}),
 	}
@@ -29,6 +29,16 @@ func TestDataCenters_List(t *testing.T) {
 			output: "json",
 			dcs:    fixtureDataCenters,
 		},
+		{
+			name:   "data center csv list",
+			output: "csv",
+			dcs:    fixtureDataCenters,
+		},
+		{
+			name:   "data center tsv list",
+			output: "tsv",
+			dcs:    fixtureDataCenters,
+		},
 		{
 			name: "empty data centers human readable",
 			dcs:  []*core.DataCenter{},
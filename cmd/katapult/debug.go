@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defines the flags used to trace API requests.
+var (
+	debugFlag     string
+	logFormatFlag string
+)
+
+// Defines where the trace is written. This is changed in tests.
+var debugOutput io.Writer = os.Stderr
+
+// Defines the header which the API sends the ID of a request in.
+const requestIDHeader = "X-Request-Id"
+
+// Used to check if a field in a URL query or JSON body holds a secret.
+func isSecretField(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"token", "password", "secret", "passphrase"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Used to trace API requests. Each attempt is written as a line of text or JSON, with the bodies if they were
+// asked for. Secrets, such as the API token, are redacted.
+type debugTransport struct {
+	next      http.RoundTripper
+	w         io.Writer
+	bodies    bool
+	jsonLines bool
+	redactor
+
+	mu sync.Mutex
+}
+
+// Used to create a debug transport from the value of --debug and --log-format. Nil is returned if debugging is
+// off. The secrets are redacted wherever they are found.
+func newDebugTransport(
+	next http.RoundTripper, debug, logFormat string, secrets ...string,
+) (*debugTransport, error) {
+	t := &debugTransport{next: next, w: debugOutput}
+	switch strings.ToLower(debug) {
+	case "", "false", "0":
+		return nil, nil
+	case "true", "1":
+	case "body":
+		t.bodies = true
+	default:
+		return nil, fmt.Errorf("invalid debug level %s, must be true or body", debug)
+	}
+	switch strings.ToLower(logFormat) {
+	case "", "text":
+	case "json":
+		t.jsonLines = true
+	default:
+		return nil, fmt.Errorf("invalid log format %s, must be text or json", logFormat)
+	}
+	t.redactor = newRedactor(secrets...)
+	return t, nil
+}
+
+// Defines a request in the trace.
+type debugEntry struct {
+	Time         string          `json:"time"`
+	Method       string          `json:"method"`
+	URL          string          `json:"url"`
+	Status       int             `json:"status,omitempty"`
+	DurationMS   int64           `json:"duration_ms"`
+	RequestID    string          `json:"request_id,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RequestBody  json.RawMessage `json:"request_body,omitempty"`
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+}
+
+// Used to redact secrets from traces and cassettes. It holds the secrets, such as the API token, which are
+// replaced wherever they are found.
+type redactor []string
+
+// Used to create a redactor, ignoring empty secrets.
+func newRedactor(secrets ...string) redactor {
+	var r redactor
+	for _, s := range secrets {
+		if s != "" {
+			r = append(r, s)
+		}
+	}
+	return r
+}
+
+// Used to replace the secrets in a string.
+func (r redactor) redact(s string) string {
+	for _, secret := range r {
+		s = strings.ReplaceAll(s, secret, redactedValue)
+	}
+	return s
+}
+
+// Used to redact the secrets in a URL, including query values with secret keys.
+func (r redactor) redactURL(u *url.URL) string {
+	c := *u
+	if c.RawQuery != "" {
+		q := c.Query()
+		keys := make([]string, 0, len(q))
+		for k := range q {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, 0, len(q))
+		for _, k := range keys {
+			for _, v := range q[k] {
+				if isSecretField(k) {
+					v = redactedValue
+				} else {
+					v = url.QueryEscape(v)
+				}
+				parts = append(parts, url.QueryEscape(k)+"="+v)
+			}
+		}
+		c.RawQuery = strings.Join(parts, "&")
+	}
+	return r.redact(c.String())
+}
+
+// Used to redact the values of secret keys in a JSON value.
+func redactJSON(v interface{}) interface{} {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, item := range x {
+			if _, isString := item.(string); isString && isSecretField(k) {
+				x[k] = redactedValue
+			} else {
+				x[k] = redactJSON(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range x {
+			x[i] = redactJSON(item)
+		}
+	}
+	return v
+}
+
+// Used to get a body for a trace or cassette. JSON bodies have their secrets redacted, and anything else is
+// written as a JSON string.
+func (r redactor) redactBody(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err == nil {
+		if b, err = json.Marshal(redactJSON(v)); err == nil {
+			return json.RawMessage(r.redact(string(b)))
+		}
+	}
+	s, _ := json.Marshal(r.redact(string(b)))
+	return s
+}
+
+// Used to write an entry to the trace.
+func (t *debugTransport) write(e *debugEntry) {
+	var line string
+	if t.jsonLines {
+		b := &bytes.Buffer{}
+		enc := json.NewEncoder(b)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(e)
+		line = b.String()
+	} else {
+		line = fmt.Sprintf("%s %s %s", e.Time, e.Method, e.URL)
+		if e.Status != 0 {
+			line += fmt.Sprintf(" %d", e.Status)
+		}
+		line += fmt.Sprintf(" %dms", e.DurationMS)
+		if e.RequestID != "" {
+			line += " request_id=" + e.RequestID
+		}
+		if e.Error != "" {
+			line += fmt.Sprintf(" error=%q", e.Error)
+		}
+		line += "\n"
+		if e.RequestBody != nil {
+			line += "> " + string(e.RequestBody) + "\n"
+		}
+		if e.ResponseBody != nil {
+			line += "< " + string(e.ResponseBody) + "\n"
+		}
+	}
+
+	// Requests can run at once when lists are fetched, so make sure lines don't get mixed up.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, _ = io.WriteString(t.w, line)
+}
+
+// RoundTrip is used to send the request and trace it.
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := timeNow()
+	e := &debugEntry{
+		Time:   start.UTC().Format(time.RFC3339Nano),
+		Method: req.Method,
+		URL:    t.redactURL(req.URL),
+	}
+	if t.bodies && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			b, _ := ioutil.ReadAll(body)
+			_ = body.Close()
+			e.RequestBody = t.redactBody(b)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	e.DurationMS = timeNow().Sub(start).Milliseconds()
+	if err != nil {
+		e.Error = t.redact(err.Error())
+		t.write(e)
+		return nil, err
+	}
+	e.Status = resp.StatusCode
+	e.RequestID = resp.Header.Get(requestIDHeader)
+
+	// Read the body so it can be traced, and then give the response a copy of it.
+	if t.bodies && strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		b, err := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			e.Error = t.redact(err.Error())
+			t.write(e)
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+		e.ResponseBody = t.redactBody(b)
+	}
+	t.write(e)
+	return resp, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/krystal/katapult-cli/internal/golden"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebug(t *testing.T) {
+	tests := []struct {
+		name string
+
+		debug     string
+		logFormat string
+		method    string
+		body      string
+		wantErr   string
+	}{
+		{
+			name:  "off",
+			debug: "",
+		},
+		{
+			name:   "text",
+			debug:  "true",
+			method: http.MethodGet,
+		},
+		{
+			name:   "text bodies",
+			debug:  "body",
+			method: http.MethodPost,
+			body:   `{"name":"My Blog","api_token":"abc"}`,
+		},
+		{
+			name:      "json",
+			debug:     "1",
+			logFormat: "json",
+			method:    http.MethodGet,
+		},
+		{
+			name:      "json bodies",
+			debug:     "body",
+			logFormat: "json",
+			method:    http.MethodPost,
+			body:      `{"name":"My Blog","api_token":"abc"}`,
+		},
+		{
+			name:    "invalid debug level",
+			debug:   "verbose",
+			wantErr: "invalid debug level verbose, must be true or body",
+		},
+		{
+			name:      "invalid log format",
+			debug:     "true",
+			logFormat: "xml",
+			wantErr:   "invalid log format xml, must be text or json",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeNow = func() time.Time { return mockNow }
+			defer func() { timeNow = time.Now }()
+			buf := &bytes.Buffer{}
+			debugOutput = buf
+			defer func() { debugOutput = os.Stderr }()
+
+			// The server echoes the token back so we can check it is redacted.
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(requestIDHeader, "req_1")
+				_, _ = w.Write([]byte(`{"organization":{"id":"org_1"},"echo":"` + r.Header.Get("Authorization") + `"}`))
+			}))
+			defer srv.Close()
+
+			dt, err := newDebugTransport(http.DefaultTransport, tt.debug, tt.logFormat, "secret-token")
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.debug == "" {
+				assert.Nil(t, dt)
+				return
+			}
+
+			req, err := http.NewRequest(tt.method, srv.URL+"/core/v1/organizations?page=2&token=abc",
+				strings.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer secret-token")
+			resp, err := (&http.Client{Transport: dt}).Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			// The body must still be readable after it has been traced.
+			b, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(b), "Bearer secret-token")
+
+			got := strings.ReplaceAll(buf.String(), srv.URL, "http://api.test")
+			assert.NotContains(t, got, "secret-token")
+			if golden.Update() {
+				golden.Set(t, []byte(got))
+			}
+			assert.Equal(t, string(golden.Get(t)), got)
+		})
+	}
+}
+
+func TestDebug_Error(t *testing.T) {
+	buf := &bytes.Buffer{}
+	debugOutput = buf
+	defer func() { debugOutput = os.Stderr }()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	dt, err := newDebugTransport(http.DefaultTransport, "true", "json")
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: dt}).Get(url + "/core/v1/organizations")
+	require.Error(t, err)
+	assert.Contains(t, buf.String(), `"error":"`)
+	assert.Contains(t, buf.String(), `"method":"GET"`)
+}
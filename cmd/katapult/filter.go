@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/augurysys/timestamp"
+)
+
+// Defines the flags used to filter and sort lists.
+var (
+	filterFlags []string
+	sortByFlag  string
+	reverseFlag bool
+)
+
+// Defines a filter set with --filter.
+type listFilter struct {
+	path  string
+	op    string
+	value string
+	re    *regexp.Regexp
+}
+
+// Used to parse a filter in the format field=value, field!=value or field~=regex.
+func parseFilter(s string) (*listFilter, error) {
+	i := strings.Index(s, "=")
+	if i < 1 {
+		return nil, fmt.Errorf("invalid filter %s, must be field=value, field!=value or field~=regex", s)
+	}
+	f := &listFilter{path: s[:i], op: "=", value: s[i+1:]}
+	switch s[i-1] {
+	case '!':
+		f.path, f.op = s[:i-1], "!="
+	case '~':
+		f.path, f.op = s[:i-1], "~="
+		re, err := regexp.Compile(f.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter regex %s: %w", f.value, err)
+		}
+		f.re = re
+	}
+	f.path = strings.TrimSpace(f.path)
+	if f.path == "" {
+		return nil, fmt.Errorf("invalid filter %s, must be field=value, field!=value or field~=regex", s)
+	}
+	return f, nil
+}
+
+// Used to check if an item matches the filter.
+func (f *listFilter) match(item reflect.Value) bool {
+	s := tableValue(valueByPath(item, f.path))
+	switch f.op {
+	case "!=":
+		return s != f.value
+	case "~=":
+		return f.re.MatchString(s)
+	default:
+		return s == f.value
+	}
+}
+
+// Used to check if a value is nil, including nil pointers.
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	r := reflect.ValueOf(v)
+	return r.Kind() == reflect.Ptr && r.IsNil()
+}
+
+// Used to check if one value should be sorted before another. Timestamps and numbers are compared by value and
+// everything else is compared as text. Nil values are sorted last.
+func lessValue(a, b interface{}) bool {
+	if isNilValue(a) || isNilValue(b) {
+		return !isNilValue(a) && isNilValue(b)
+	}
+	if x, ok := a.(*timestamp.Timestamp); ok {
+		if y, ok := b.(*timestamp.Timestamp); ok {
+			return x.Time().Before(y.Time())
+		}
+	}
+
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return x < y
+		}
+	}
+
+	return tableValue(a) < tableValue(b)
+}
+
+// Used to filter and sort a list.
+func filterAndSortList(list reflect.Value, filters []*listFilter) (interface{}, error) {
+	// Check the fields exist.
+	t := list.Type().Elem()
+	for _, f := range filters {
+		if !hasPath(t, f.path) {
+			return nil, fmt.Errorf("unknown field %s", f.path)
+		}
+	}
+	if sortByFlag != "" && !hasPath(t, sortByFlag) {
+		return nil, fmt.Errorf("unknown field %s", sortByFlag)
+	}
+
+	// Filter the items into a new slice of the same type.
+	result := reflect.MakeSlice(list.Type(), 0, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		item := list.Index(i)
+		matches := true
+		for _, f := range filters {
+			if !f.match(item) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			result = reflect.Append(result, item)
+		}
+	}
+
+	// Sort the items.
+	if sortByFlag != "" {
+		keys := make([]interface{}, result.Len())
+		for i := range keys {
+			keys[i] = valueByPath(result.Index(i), sortByFlag)
+		}
+		swap := reflect.Swapper(result.Interface())
+		sort.Stable(&valueSorter{keys: keys, swap: swap, reverse: reverseFlag})
+	} else if reverseFlag {
+		swap := reflect.Swapper(result.Interface())
+		for i, j := 0, result.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	return result.Interface(), nil
+}
+
+// Used to sort a slice by keys which have been taken from its items. Nil keys are sorted last, even when the
+// order is reversed.
+type valueSorter struct {
+	keys    []interface{}
+	swap    func(i, j int)
+	reverse bool
+}
+
+func (s *valueSorter) Len() int {
+	return len(s.keys)
+}
+
+func (s *valueSorter) Less(i, j int) bool {
+	if s.reverse && !isNilValue(s.keys[i]) && !isNilValue(s.keys[j]) {
+		return lessValue(s.keys[j], s.keys[i])
+	}
+	return lessValue(s.keys[i], s.keys[j])
+}
+
+func (s *valueSorter) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
+
+// Used to apply --filter, --sort-by and --reverse to the lists of an output.
+func applyFilterAndSort(output Output) (Output, error) {
+	g, ok := output.(*genericOutput)
+	if !ok {
+		return nil, errors.New("--filter, --sort-by and --reverse are only supported by list commands")
+	}
+	filters := make([]*listFilter, len(filterFlags))
+	for i, s := range filterFlags {
+		f, err := parseFilter(s)
+		if err != nil {
+			return nil, err
+		}
+		filters[i] = f
+	}
+
+	result, ok, err := transformLists(g, func(list reflect.Value) (interface{}, error) {
+		return filterAndSortList(list, filters)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("--filter, --sort-by and --reverse are only supported by list commands")
+	}
+	return result, nil
+}
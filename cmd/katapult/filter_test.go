@@ -0,0 +1,207 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/augurysys/timestamp"
+	"github.com/krystal/go-katapult/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Defines virtual machines with different states, packages and creation times for the filter tests.
+var filterVirtualMachines = []*core.VirtualMachine{
+	{
+		ID:        "vm_1",
+		Name:      "web-1",
+		FQDN:      "web-1.loge.katapult.cloud",
+		State:     core.VirtualMachineStarted,
+		Package:   &core.VirtualMachinePackage{Name: "Rock 3"},
+		CreatedAt: timestamp.Unix(1627819200, 0),
+	},
+	{
+		ID:        "vm_2",
+		Name:      "db-1",
+		FQDN:      "db-1.loge.katapult.cloud",
+		State:     core.VirtualMachineStopped,
+		Package:   &core.VirtualMachinePackage{Name: "Rock 10"},
+		CreatedAt: timestamp.Unix(1627732800, 0),
+	},
+	{
+		ID:        "vm_3",
+		Name:      "web-2",
+		FQDN:      "web-2.loge.katapult.cloud",
+		State:     core.VirtualMachineStarted,
+		Package:   &core.VirtualMachinePackage{Name: "Rock 10"},
+		CreatedAt: timestamp.Unix(1627905600, 0),
+	},
+	{
+		ID:   "vm_4",
+		Name: "empty",
+	},
+}
+
+func Test_parseFilter(t *testing.T) {
+	tests := []struct {
+		name string
+
+		filter  string
+		want    *listFilter
+		wantErr string
+	}{
+		{
+			name:   "equals",
+			filter: "state=started",
+			want:   &listFilter{path: "state", op: "=", value: "started"},
+		},
+		{
+			name:   "not equals",
+			filter: "package.name!=Rock 3",
+			want:   &listFilter{path: "package.name", op: "!=", value: "Rock 3"},
+		},
+		{
+			name:   "value containing equals",
+			filter: "description=a=b",
+			want:   &listFilter{path: "description", op: "=", value: "a=b"},
+		},
+		{
+			name:    "no operator",
+			filter:  "state",
+			wantErr: "invalid filter state, must be field=value, field!=value or field~=regex",
+		},
+		{
+			name:    "no field",
+			filter:  "!=started",
+			wantErr: "invalid filter !=started, must be field=value, field!=value or field~=regex",
+		},
+		{
+			name:    "invalid regex",
+			filter:  "name~=web-(",
+			wantErr: "invalid filter regex web-(: error parsing regexp: missing closing ): `web-(`",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f)
+		})
+	}
+}
+
+func TestFilter_VMs(t *testing.T) {
+	tests := []struct {
+		name string
+
+		filters []string
+		sortBy  string
+		reverse bool
+		output  string
+		wantErr string
+	}{
+		{
+			name:    "equals",
+			filters: []string{"state=started"},
+		},
+		{
+			name:    "not equals with dotted path",
+			filters: []string{"package.name!=Rock 3"},
+		},
+		{
+			name:    "regex",
+			filters: []string{"name~=^web-"},
+		},
+		{
+			name:    "multiple filters",
+			filters: []string{"state=started", "package.name=Rock 10"},
+		},
+		{
+			name:   "sort by name",
+			sortBy: "name",
+		},
+		{
+			name:    "sort by created at reversed",
+			sortBy:  "created_at",
+			reverse: true,
+			output:  "wide",
+		},
+		{
+			name:    "filter and sort json",
+			filters: []string{"name~=web"},
+			sortBy:  "CreatedAt",
+			output:  "json",
+		},
+		{
+			name:    "unknown filter field",
+			filters: []string{"colour=red"},
+			wantErr: "unknown field colour",
+		},
+		{
+			name:    "unknown sort field",
+			sortBy:  "colour",
+			wantErr: "unknown field colour",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := map[string]vmPages{"loge": {filterVirtualMachines[:2], filterVirtualMachines[2:]}}
+			cmd := virtualMachinesCmd(
+				&vmsClient{organizationSubdomainPages: pages}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd.SetArgs([]string{"list", "loge"})
+			filterFlags = tt.filters
+			sortByFlag = tt.sortBy
+			reverseFlag = tt.reverse
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			filterFlags = nil
+			sortByFlag = ""
+			reverseFlag = false
+			outputFlag = ""
+		})
+	}
+}
+
+func TestFilter_Networks(t *testing.T) {
+	tests := []struct {
+		name string
+
+		filters []string
+		sortBy  string
+		reverse bool
+	}{
+		{
+			name:    "filter both lists",
+			filters: []string{"id!=pognet"},
+		},
+		{
+			name:    "reverse",
+			reverse: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := networksCmd(mockNetworkList{})
+			cmd.SetArgs([]string{"ls", "--id", "pog-id"})
+			filterFlags = tt.filters
+			sortByFlag = tt.sortBy
+			reverseFlag = tt.reverse
+			assertCobraCommand(t, cmd, "", "")
+			filterFlags = nil
+			sortByFlag = ""
+			reverseFlag = false
+		})
+	}
+}
+
+func TestFilter_NotList(t *testing.T) {
+	client := &vmsClient{organizationIDPages: map[string]vmPages{"1": {{fixtureVirtualMachine}}}}
+	cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	cmd.SetArgs([]string{"get", "--id=vm_rrmEoG6CKUX0IKgX"})
+	sortByFlag = "name"
+	assertCobraCommand(t, cmd, "--filter, --sort-by and --reverse are only supported by list commands", "")
+	sortByFlag = ""
+}
@@ -1,18 +1,130 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/krystal/go-katapult/core"
 	"github.com/krystal/katapult-cli/config"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// Defines the status codes which the CLI exits with when a command times out or is interrupted. These are the
+// same as the ones used by timeout(1) and shells.
+const (
+	exitTimeout   = 124
+	exitInterrupt = 130
+)
+
+// Defines the annotation for commands which don't use the API token from the credential store, so they can run
+// when it can't be read.
+const noTokenAnnotation = "katapult_no_token"
+
+// Returned by run when the command is interrupted with Ctrl-C or SIGTERM.
+var errInterrupted = errors.New("interrupted")
+
+// Defines a root flag which sets a config key. The flag has the same type as its default value.
+type configFlag struct {
+	name  string
+	key   string
+	value interface{}
+	usage string
+}
+
+// Used to get the flags which set config keys.
+func configFlags() []configFlag {
+	apiURLDefault := config.Defaults.APIURL
+	if apiURLDefault != "" {
+		apiURLDefault = " (default: " + apiURLDefault + ")"
+	}
+	tokenDefault := config.Defaults.APIToken
+	if tokenDefault != "" {
+		tokenDefault = " (default: " + tokenDefault + ")"
+	}
+	return []configFlag{
+		{"api-url", "api_url", "", "URL for Katapult API" + apiURLDefault},
+		{"api-token", "api_token", "", "Katapult API Token" + tokenDefault},
+		{"retries", "retries", defaultRetries,
+			"number of times to retry rate limited requests, or failed ones which are safe to send twice"},
+		{"retry-max-wait", "retry_max_wait", defaultRetryMaxWait, "longest time to wait between retries"},
+		{"https-proxy", "https_proxy", "", "URL of the proxy to send API requests through (default: $HTTPS_PROXY)"},
+		{"ca-bundle", "ca_bundle", "", "path of a PEM file with extra certificate authorities to trust"},
+		{"client-cert", "client_cert", "", "path of a PEM client certificate to send to the API"},
+		{"client-key", "client_key", "", "path of the PEM key of the client certificate"},
+		{"insecure-skip-verify", "insecure_skip_verify", false,
+			"don't check the certificate of the API, this is insecure"},
+		{"profile", "profile", "", "The config profile to use."},
+	}
+}
+
+// Used to add the flags which set config keys, and bind them to the config.
+func addConfigFlags(flags *pflag.FlagSet, conf *config.Config) error {
+	for _, f := range configFlags() {
+		switch v := f.value.(type) {
+		case int:
+			flags.Int(f.name, v, f.usage)
+		case time.Duration:
+			flags.Duration(f.name, v, f.usage)
+		case bool:
+			flags.Bool(f.name, v, f.usage)
+		default:
+			flags.String(f.name, fmt.Sprint(v), f.usage)
+		}
+		if err := conf.BindPFlag(f.key, flags.Lookup(f.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Used to add the flags which change how the output of commands is shown and traced.
+func addOutputFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&outputFlag, "output", "o", os.Getenv("KATAPULT_OUTPUT"),
+		"output type (yaml, json, ndjson, text, wide, csv, tsv)")
+	flags.StringSliceVar(&columnsFlag, "columns", nil,
+		"comma separated columns to show for list commands, such as name,fqdn,package.name")
+	flags.BoolVar(&noHeadersFlag, "no-headers", false, "don't show the headers of tables")
+	flags.StringArrayVar(&filterFlags, "filter", nil,
+		"filter lists with field=value, field!=value or field~=regex, can be repeated")
+	flags.StringVar(&sortByFlag, "sort-by", "", "field to sort lists by")
+	flags.BoolVar(&reverseFlag, "reverse", false, "reverse the order of lists")
+	flags.IntVar(&limitFlag, "limit", 0, "maximum number of items to show for list commands")
+	flags.IntVar(&pageFlag, "page", 0, "page of the list to show, instead of every page")
+	flags.IntVar(&pageSizeFlag, "page-size", 0, "number of items on each page of a list (default: the API default)")
+	flags.IntVar(&parallelFlag, "parallel", parallelFlag, "number of pages of a list to fetch at once")
+	flags.StringVar(&queryFlag, "query", "", "JMESPath query to run over the output, such as [].name")
+	flags.StringVar(&templateFlag, "format", "",
+		"defines the output template for text, or @path to load it from a file")
+
+	flags.StringVar(&debugFlag, "debug", os.Getenv("KATAPULT_DEBUG"),
+		"trace API requests to stderr, use --debug=body to include the bodies")
+	flags.Lookup("debug").NoOptDefVal = "true"
+	flags.StringVar(&logFormatFlag, "log-format", os.Getenv("KATAPULT_LOG_FORMAT"),
+		"format of the debug trace (text, json)")
+}
+
+// Used to run the CLI with the arguments it was started with. Any error is written to stderr, including ones from
+// loading the config before the command runs.
 func run() error {
+	err := execute()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Error:", err.Error())
+	}
+	return err
+}
+
+// Used to set up the root command from the config and execute it.
+func execute() error {
 	var (
 		configFileFlag string
-		configURLFlag  string
-		configAPIToken string
+		timeoutFlag    time.Duration
 	)
 
 	conf, err := config.New()
@@ -20,7 +132,10 @@ func run() error {
 		return err
 	}
 
-	var help bool
+	var (
+		help      bool
+		clientErr error
+	)
 	rootCmd := &cobra.Command{
 		Use:   "katapult",
 		Short: "katapult CLI tool",
@@ -36,37 +151,39 @@ func run() error {
 				}
 				os.Exit(0)
 			}
-			return nil
+			// The config commands can still be used with an unknown profile or invalid client settings, so that
+			// they can be fixed.
+			if isConfigCommand(cmd) {
+				return nil
+			}
+			if err := conf.ProfileError(); err != nil {
+				return err
+			}
+			if clientErr != nil {
+				return clientErr
+			}
+			if _, ok := cmd.Annotations[noTokenAnnotation]; ok {
+				return nil
+			}
+			return conf.TokenError()
 		},
-		SilenceUsage: true,
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
 
 	rootFlags := rootCmd.PersistentFlags()
 
 	rootFlags.BoolVarP(&help, "help", "h", false, "Display the help for the command/root.")
 
-	rootFlags.StringVarP(&outputFlag, "output", "o", "", "output type (yaml, json, text)")
-	rootFlags.StringVar(&templateFlag, "format", "", "defines the output template for text")
+	addOutputFlags(rootFlags)
+
+	rootFlags.DurationVar(&timeoutFlag, "timeout", 0,
+		"maximum time the command can take, such as 30s (default: no limit)")
 
 	rootFlags.StringVar(&configFileFlag, "config-path", "",
 		"config file (default: $HOME/.katapult/katapult.yaml)")
 
-	apiURLDefault := config.Defaults.APIURL
-	if apiURLDefault != "" {
-		apiURLDefault = " (default: " + apiURLDefault + ")"
-	}
-	rootFlags.StringVar(&configURLFlag, "api-url", "", "URL for Katapult API"+apiURLDefault)
-	err = conf.BindPFlag("api_url", rootFlags.Lookup("api-url"))
-	if err != nil {
-		return err
-	}
-	tokenDefault := config.Defaults.APIToken
-	if tokenDefault != "" {
-		tokenDefault = " (default: " + tokenDefault + ")"
-	}
-	rootFlags.StringVar(&configAPIToken, "api-token", "", "Katapult API Token"+tokenDefault)
-	err = conf.BindPFlag("api_token", rootFlags.Lookup("api-token"))
-	if err != nil {
+	if err = addConfigFlags(rootFlags, conf); err != nil {
 		return err
 	}
 
@@ -74,6 +191,7 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	resetSliceFlags(rootFlags)
 
 	if configFileFlag != "" {
 		conf.SetConfigFile(configFileFlag)
@@ -82,19 +200,87 @@ func run() error {
 	if err = conf.Load(); err != nil {
 		return err
 	}
+	defaultOrganization = conf.Organization
 
-	cl, err := newClient(conf)
-	if err != nil {
+	if err = setBaseTransport(conf); err != nil {
 		return err
 	}
 
+	var cl core.RequestMaker
+	cl, clientErr = newClient(conf)
+
+	addCommands(rootCmd, conf, cl)
+
+	return executeContext(rootCmd, timeoutFlag)
+}
+
+// Used to record the API requests to a cassette, or replay them from one, if asked to. Recorded requests are sent
+// with the proxy and TLS settings in the config.
+func setBaseTransport(conf *config.Config) error {
+	record, replay := os.Getenv(recordEnv), os.Getenv(replayEnv)
+	var (
+		network http.RoundTripper
+		err     error
+	)
+	if record != "" {
+		if network, err = newHTTPTransport(conf); err != nil {
+			return err
+		}
+	}
+	baseTransport, err = newCassetteTransport(network, record, replay, conf.APIToken)
+	return err
+}
+
+// Used to execute the root command. The command is cancelled if it is interrupted or takes longer than the timeout,
+// and the error says which of these stopped it.
+func executeContext(rootCmd *cobra.Command, timeout time.Duration) error {
+	// Once the command has been interrupted, the signals are no longer caught so that a second Ctrl-C kills it
+	// straight away.
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-interrupted.Done()
+		stop()
+	}()
+	ctx := interrupted
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := rootCmd.ExecuteContext(ctx)
+	if err != nil {
+		// Errors caused by the context being cancelled are replaced so it is clear why the command stopped.
+		switch {
+		case interrupted.Err() != nil:
+			err = errInterrupted
+		case errors.Is(err, errWaitTimeout):
+			// The command already says what it was waiting for.
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			err = fmt.Errorf("%w after %s", errWaitTimeout, timeout)
+		}
+	}
+	return err
+}
+
+// Used to add the commands to the root command, using the client to connect to the API.
+func addCommands(rootCmd *cobra.Command, conf *config.Config, cl core.RequestMaker) {
 	rootCmd.AddCommand(
-		authCommand(conf),
+		authCommand(conf, core.NewOrganizationsClient(cl), func(conf *config.Config) (organisationsListClient, error) {
+			c, err := newClient(conf)
+			if err != nil {
+				return nil, err
+			}
+			return core.NewOrganizationsClient(c), nil
+		}),
+		whoamiCommand(conf, core.NewOrganizationsClient(cl)),
 		versionCommand(),
 		configCommand(conf),
 		dataCentersCmd(core.NewDataCentersClient(cl)),
 		networksCmd(core.NewNetworksClient(cl)),
 		organizationsCmd(core.NewOrganizationsClient(cl)),
+		trashCmd(core.NewTrashObjectsClient(cl)),
 		virtualMachinesCmd(
 			core.NewVirtualMachinesClient(cl),
 			core.NewOrganizationsClient(cl),
@@ -105,16 +291,36 @@ func run() error {
 			core.NewSSHKeysClient(cl),
 			core.NewTagsClient(cl),
 			core.NewVirtualMachineBuildsClient(cl),
+			core.NewTasksClient(cl),
 			nil, nil),
 	)
+}
+
+// Used to get the status code to exit with after run returns.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errInterrupted):
+		return exitInterrupt
+	case errors.Is(err, errWaitTimeout):
+		return exitTimeout
+	default:
+		// Ensure we exit with status code 1. The actual printing is done by run.
+		return 1
+	}
+}
 
-	return rootCmd.Execute()
+// Used to empty the flags which add to a list after they have been parsed. The flags are parsed again when the
+// command is executed, so this stops their values from being added twice.
+func resetSliceFlags(flags *pflag.FlagSet) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok && f.Changed {
+			_ = v.Replace(nil)
+		}
+	})
 }
 
 func main() {
-	err := run()
-	if err != nil {
-		// Ensure we exit with status code 1. The actual printing is done by Cobra.
-		os.Exit(1)
-	}
+	os.Exit(exitCode(run()))
 }
@@ -5,12 +5,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/krystal/go-katapult"
 	"github.com/krystal/go-katapult/core"
+	"github.com/krystal/katapult-cli/config"
 	"github.com/krystal/katapult-cli/internal/golden"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -91,6 +100,9 @@ func (m mockDataCentersClient) Get(
 	return nil, nil, fmt.Errorf("unknown datacentre")
 }
 
+// Defines the time which is used as the current time in tests.
+var mockNow = time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+
 var fixtureOrganizations = []*core.Organization{
 	{
 		ID:        "loge",
@@ -124,3 +136,158 @@ var fixtureDataCenters = []*core.DataCenter{
 		},
 	},
 }
+
+func Test_resetSliceFlags(t *testing.T) {
+	var columns []string
+	var output string
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringSliceVar(&columns, "columns", nil, "")
+	flags.StringVar(&output, "output", "", "")
+
+	args := []string{"--columns", "name,fqdn", "--columns", "state", "--output", "csv"}
+	require.NoError(t, flags.Parse(args))
+	resetSliceFlags(flags)
+	require.NoError(t, flags.Parse(args))
+
+	assert.Equal(t, []string{"name", "fqdn", "state"}, columns)
+	assert.Equal(t, "csv", output)
+}
+
+func Test_exitCode(t *testing.T) {
+	assert.Equal(t, 0, exitCode(nil))
+	assert.Equal(t, 1, exitCode(errors.New("test error")))
+	assert.Equal(t, 124, exitCode(fmt.Errorf("%w after 1s", errWaitTimeout)))
+	assert.Equal(t, 124, exitCode(fmt.Errorf("%w waiting for task task_1 to finish", errWaitTimeout)))
+	assert.Equal(t, 130, exitCode(errInterrupted))
+}
+
+// Used to create an API which doesn't respond until the request is cancelled. started is closed when the first
+// request arrives.
+func newHungAPI() (srv *httptest.Server, started chan struct{}) {
+	started = make(chan struct{})
+	var once sync.Once
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() { close(started) })
+		<-r.Context().Done()
+	}))
+	return srv, started
+}
+
+func TestRun_Timeout(t *testing.T) {
+	srv, _ := newHungAPI()
+	defer srv.Close()
+
+	_, _, err := runCLI(t, map[string]string{
+		"KATAPULT_API_TOKEN": "test-token",
+		"KATAPULT_API_URL":   srv.URL,
+	}, "dc", "list", "--timeout", "50ms")
+	require.EqualError(t, err, "timed out after 50ms")
+	assert.Equal(t, exitTimeout, exitCode(err))
+}
+
+func TestRun_Interrupt(t *testing.T) {
+	srv, started := newHungAPI()
+	defer srv.Close()
+
+	go func() {
+		<-started
+		p, _ := os.FindProcess(os.Getpid())
+		_ = p.Signal(os.Interrupt)
+	}()
+	_, _, err := runCLI(t, map[string]string{
+		"KATAPULT_API_TOKEN": "test-token",
+		"KATAPULT_API_URL":   srv.URL,
+	}, "dc", "list")
+	require.Equal(t, errInterrupted, err)
+	assert.Equal(t, exitInterrupt, exitCode(err))
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		wantErr string
+	}{
+		{
+			name:    "invalid flag",
+			args:    []string{"--retries", "abc", "version"},
+			wantErr: `invalid argument "abc" for "--retries" flag: strconv.ParseInt: parsing "abc": invalid syntax`,
+		},
+		{
+			name:    "invalid config",
+			args:    []string{"--retries", "-1", "version"},
+			wantErr: "invalid retries: -1",
+		},
+		{
+			name:    "missing CA bundle",
+			args:    []string{"--ca-bundle", "/missing/ca.pem", "dc", "list"},
+			wantErr: "failed to read CA bundle: open /missing/ca.pem: no such file or directory",
+		},
+		{
+			name:    "unknown profile",
+			args:    []string{"--profile", "unknown", "dc", "list"},
+			wantErr: "the profile unknown does not exist",
+		},
+		{
+			name:    "command error",
+			args:    []string{"dc", "get"},
+			wantErr: "accepts 1 arg(s), received 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, err := runCLI(t, map[string]string{}, tt.args...)
+			require.EqualError(t, err, tt.wantErr)
+			assert.Equal(t, "", stdout)
+			assert.Equal(t, "Error: "+tt.wantErr+"\n", stderr)
+		})
+	}
+}
+
+func TestRun_UnknownProfile(t *testing.T) {
+	// The config commands still work so that the profile can be fixed.
+	stdout, stderr, err := runCLI(t, map[string]string{"KATAPULT_PROFILE": "unknown"},
+		"config", "list-profiles", "-o", "json")
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"default","api_url":"","organization":"","active":false}]`, stdout)
+	assert.Equal(t, "", stderr)
+}
+
+func TestRun_InvalidClientSettings(t *testing.T) {
+	confPath := filepath.Join(t.TempDir(), "katapult.yaml")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte("retries: abc\n"), 0o600))
+	env := func() map[string]string { return map[string]string{config.ConfigEnv: confPath} }
+
+	_, _, err := runCLI(t, env(), "dc", "list")
+	require.EqualError(t, err, "invalid retries: abc")
+
+	// The config commands still work so that the setting can be fixed.
+	_, stderr, err := runCLI(t, env(), "config", "unset", "retries")
+	require.NoError(t, err)
+	assert.Equal(t, "", stderr)
+	b, err := ioutil.ReadFile(confPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "retries")
+}
+
+func TestRun_TokenError(t *testing.T) {
+	dir := t.TempDir()
+	confPath := filepath.Join(dir, "katapult.yaml")
+	require.NoError(t, ioutil.WriteFile(confPath, []byte("credential_store: file\n"), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "credentials.enc"), []byte("corrupt"), 0o600))
+	env := func() map[string]string {
+		return map[string]string{config.ConfigEnv: confPath, "KATAPULT_CREDENTIALS_PASSPHRASE": "hunter2"}
+	}
+
+	_, _, err := runCLI(t, env(), "dc", "list")
+	require.EqualError(t, err,
+		"failed to get the API token from the file credential store: the credentials file is corrupt")
+
+	// Commands which don't use the token still work.
+	for _, args := range [][]string{{"version"}, {"config", "get", "credential_store"}} {
+		_, stderr, err := runCLI(t, env(), args...)
+		require.NoError(t, err)
+		assert.Equal(t, "", stderr)
+	}
+}
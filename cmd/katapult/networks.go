@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"fmt"
 
 	"github.com/krystal/go-katapult"
 	"github.com/krystal/go-katapult/core"
@@ -21,6 +20,50 @@ const networksListFormat = `Networks:
 {{ Table (StringSlice "Name" "ID") (MultipleRows .virtual_networks "Name" "ID") }}
 `
 
+// Defines a row of networks list when it is output as a table. Networks and virtual networks are listed together,
+// and the type says which each row is.
+type networkListItem struct {
+	Type       string           `json:"type"`
+	ID         string           `json:"id"`
+	Name       string           `json:"name"`
+	Permalink  string           `json:"permalink,omitempty"`
+	DataCenter *core.DataCenter `json:"data_center,omitempty"`
+}
+
+// Defines the columns of networks list when it is output as a table.
+var networksListColumns = []*outputColumn{
+	{Header: "Type", Path: "Type"},
+	{Header: "Name", Path: "Name"},
+	{Header: "ID", Path: "ID"},
+	{Header: "Data Center", Path: "DataCenter.Permalink"},
+}
+
+// Defines the columns of networks list -o wide.
+var networksListWideColumns = []*outputColumn{
+	{Header: "Type", Path: "Type"},
+	{Header: "ID", Path: "ID"},
+	{Header: "Name", Path: "Name"},
+	{Header: "Permalink", Path: "Permalink"},
+	{Header: "Data Center", Path: "DataCenter.Permalink"},
+	{Header: "Data Center Name", Path: "DataCenter.Name"},
+}
+
+// Used to list networks and virtual networks together, so they can be output as a table.
+func networkListItems(nets []*core.Network, vnets []*core.VirtualNetwork) []*networkListItem {
+	items := make([]*networkListItem, 0, len(nets)+len(vnets))
+	for _, n := range nets {
+		items = append(items, &networkListItem{
+			Type: "network", ID: n.ID, Name: n.Name, Permalink: n.Permalink, DataCenter: n.DataCenter,
+		})
+	}
+	for _, n := range vnets {
+		items = append(items, &networkListItem{
+			Type: "virtual network", ID: n.ID, Name: n.Name, DataCenter: n.DataCenter,
+		})
+	}
+	return items
+}
+
 func networksCmd(client networksListClient) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "networks",
@@ -35,14 +78,9 @@ func networksCmd(client networksListClient) *cobra.Command {
 		Short:   "Get list of networks available to a Organization",
 		Long:    "Get list of networks available to a Organization.",
 		RunE: outputWrapper(func(cmd *cobra.Command, _ []string) (Output, error) {
-			id := cmd.Flag("id").Value.String()
-			ref := core.OrganizationRef{ID: id}
-			if id == "" {
-				subdomain := cmd.Flag("subdomain").Value.String()
-				if subdomain == "" {
-					return nil, fmt.Errorf("both ID and subdomain are unset")
-				}
-				ref = core.OrganizationRef{SubDomain: subdomain}
+			ref, err := getOrgRef(cmd)
+			if err != nil {
+				return nil, err
 			}
 
 			nets, vnets, _, err := client.List(cmd.Context(), ref)
@@ -50,6 +88,15 @@ func networksCmd(client networksListClient) *cobra.Command {
 				return nil, err
 			}
 
+			// Tables can only show one list, so the networks and virtual networks are put in the same one.
+			if isTableOutput() {
+				return &genericOutput{
+					item:        networkListItems(nets, vnets),
+					columns:     networksListColumns,
+					wideColumns: networksListWideColumns,
+				}, nil
+			}
+
 			if vnets == nil {
 				vnets = []*core.VirtualNetwork{}
 			}
@@ -105,10 +105,12 @@ func TestNetworks_List(t *testing.T) {
 	tests := []struct {
 		name string
 
-		args    []string
-		output  string
-		stderr  string
-		wantErr string
+		args       []string
+		defaultOrg string
+		output     string
+		columns    []string
+		stderr     string
+		wantErr    string
 	}{
 		{
 			name: "Test listing pog-id human readable",
@@ -128,12 +130,37 @@ func TestNetworks_List(t *testing.T) {
 			args:   []string{"ls", "--subdomain", "pog-subdomain"},
 			output: "json",
 		},
+		{
+			name:   "Test listing pog-id csv",
+			args:   []string{"ls", "--id", "pog-id"},
+			output: "csv",
+		},
+		{
+			name:   "Test listing pog-id wide",
+			args:   []string{"ls", "--id", "pog-id"},
+			output: "wide",
+		},
+		{
+			name:    "Test listing pog-id columns",
+			args:    []string{"ls", "--id", "pog-id"},
+			columns: []string{"name", "type"},
+		},
 		{
 			name:    "No flags provided",
 			args:    []string{"ls"},
 			stderr:  "Error: both ID and subdomain are unset\n",
 			wantErr: "both ID and subdomain are unset",
 		},
+		{
+			name:       "Test listing default organization subdomain",
+			args:       []string{"ls"},
+			defaultOrg: "pog-subdomain",
+		},
+		{
+			name:       "Test listing flag overrides default organization",
+			args:       []string{"ls", "--id", "pog-id"},
+			defaultOrg: "pog-subdomain",
+		},
 	}
 
 	for _, tt := range tests {
@@ -141,8 +168,12 @@ func TestNetworks_List(t *testing.T) {
 			cmd := networksCmd(mockNetworkList{})
 			cmd.SetArgs(tt.args)
 			outputFlag = tt.output
+			columnsFlag = tt.columns
+			defaultOrganization = tt.defaultOrg
 			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
 			outputFlag = ""
+			columnsFlag = nil
+			defaultOrganization = ""
 		})
 	}
 }
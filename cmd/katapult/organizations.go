@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/krystal/go-katapult"
 	"github.com/krystal/go-katapult/core"
@@ -14,7 +16,52 @@ type organisationsListClient interface {
 	) ([]*core.Organization, *katapult.Response, error)
 }
 
-const organizationsListFormat = `{{ Table (StringSlice "Name" "Subdomain") (MultipleRows . "Name" "SubDomain") }}`
+// Defines the default organization from the active config profile. This is used when an organization is not
+// specified.
+var defaultOrganization string
+
+// Used to get a reference to the default organization. Organization IDs are prefixed with "org_", so anything
+// else is treated as a subdomain.
+func defaultOrgRef() (core.OrganizationRef, bool) {
+	switch {
+	case defaultOrganization == "":
+		return core.OrganizationRef{}, false
+	case strings.HasPrefix(defaultOrganization, "org_"):
+		return core.OrganizationRef{ID: defaultOrganization}, true
+	default:
+		return core.OrganizationRef{SubDomain: defaultOrganization}, true
+	}
+}
+
+// Used to get the organization from the --id and --subdomain flags, falling back to the default organization.
+func getOrgRef(cmd *cobra.Command) (core.OrganizationRef, error) {
+	if id := cmd.Flag("id").Value.String(); id != "" {
+		return core.OrganizationRef{ID: id}, nil
+	}
+	if subdomain := cmd.Flag("subdomain").Value.String(); subdomain != "" {
+		return core.OrganizationRef{SubDomain: subdomain}, nil
+	}
+	if ref, ok := defaultOrgRef(); ok {
+		return ref, nil
+	}
+	return core.OrganizationRef{}, fmt.Errorf("both ID and subdomain are unset")
+}
+
+// Defines the columns of org list.
+var organizationsListColumns = []*outputColumn{
+	{Header: "Name", Path: "Name"},
+	{Header: "Subdomain", Path: "SubDomain"},
+}
+
+// Defines the columns of org list -o wide.
+var organizationsListWideColumns = []*outputColumn{
+	{Header: "ID", Path: "ID"},
+	{Header: "Name", Path: "Name"},
+	{Header: "Subdomain", Path: "SubDomain"},
+	{Header: "Personal", Path: "Personal"},
+	{Header: "Suspended", Path: "Suspended"},
+	{Header: "Created At", Path: "CreatedAt"},
+}
 
 func organizationsCmd(client organisationsListClient) *cobra.Command {
 	cmd := &cobra.Command{
@@ -36,8 +83,9 @@ func organizationsCmd(client organisationsListClient) *cobra.Command {
 			}
 
 			return &genericOutput{
-				item:                orgs,
-				defaultTextTemplate: organizationsListFormat,
+				item:        orgs,
+				columns:     organizationsListColumns,
+				wideColumns: organizationsListWideColumns,
 			}, nil
 		}),
 	}
@@ -2,14 +2,19 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
+	"github.com/augurysys/timestamp"
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -17,6 +22,15 @@ import (
 
 var outputFlag, templateFlag string
 
+// Defines the columns set with --columns and whether headers are hidden with --no-headers.
+var (
+	columnsFlag   []string
+	noHeadersFlag bool
+)
+
+// Used to get the current time. This is a variable so that it can be mocked in tests.
+var timeNow = time.Now
+
 // Output is used to define the interface of outputs.
 type Output interface {
 	// JSON is used to write out the JSON output.
@@ -27,6 +41,51 @@ type Output interface {
 
 	// Text is used to render a template. If string is blank, uses the default.
 	Text(w io.Writer, template string) error
+
+	// CSV is used to write out the columns as delimited values with a header row.
+	CSV(w io.Writer, comma rune) error
+
+	// NDJSON is used to write out JSON lines, with each item of a list on its own line.
+	NDJSON(w io.Writer) error
+}
+
+// Defines a column of a list. The path is the dot separated path to the field, like the keys of MultipleRows.
+type outputColumn struct {
+	Header string
+	Path   string
+}
+
+// Used to get the headers and rows of the columns of a list.
+func columnRows(items interface{}, columns []*outputColumn) ([]string, [][]interface{}) {
+	headers := make([]string, len(columns))
+	paths := make([]string, len(columns))
+	for i, c := range columns {
+		headers[i] = c.Header
+		paths[i] = c.Path
+	}
+	return headers, multipleRows(items, paths...)
+}
+
+// Used to format a value for use within a table.
+func tableValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if r := reflect.ValueOf(v); r.Kind() == reflect.Ptr && r.IsNil() {
+		return ""
+	}
+	switch x := v.(type) {
+	case *timestamp.Timestamp:
+		if x == nil {
+			return ""
+		}
+		return x.Time().UTC().Format(time.RFC3339)
+	case float64:
+		// Numbers from JSON are float64, so make sure large numbers aren't written with an exponent.
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
 }
 
 // Used to render a table.
@@ -47,13 +106,15 @@ func table(columns []string, rows [][]interface{}) string {
 	t.SetBorder(false)
 	t.SetTablePadding("\t")
 	t.SetNoWhiteSpace(true)
-	t.SetHeader(columns)
+	if !noHeadersFlag {
+		t.SetHeader(columns)
+	}
 
 	strrows := make([][]string, len(rows))
 	for i, row := range rows {
 		strrow := make([]string, len(row))
 		for x, v := range row {
-			strrow[x] = fmt.Sprint(v)
+			strrow[x] = tableValue(v)
 		}
 		strrows[i] = strrow
 	}
@@ -91,6 +152,67 @@ func singleRow(items ...interface{}) [][]interface{} {
 	return [][]interface{}{items}
 }
 
+// Used to find a struct field by name. The name can either be the field name, ignoring case, or the JSON name.
+func findField(t reflect.Type, name string) (reflect.StructField, bool) {
+	if f, ok := t.FieldByName(name); ok {
+		return f, true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(f.Name, name) || (jsonName != "" && jsonName == name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// Used to get a value by a dot separated path of fields or map keys. Nil is returned if the path can't be
+// followed, such as when a struct along the path is nil.
+func valueByPath(v reflect.Value, path string) interface{} {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		switch {
+		case !v.IsValid():
+			return nil
+		case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		case v.Kind() == reflect.Struct:
+			f, ok := findField(v.Type(), name)
+			if !ok {
+				return nil
+			}
+			v = v.FieldByIndex(f.Index)
+		default:
+			return nil
+		}
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// Used to check if a dot separated path of fields exists on a type.
+func hasPath(t reflect.Type, path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		f, ok := findField(t, name)
+		if !ok {
+			return false
+		}
+		t = f.Type
+	}
+	return true
+}
+
 // Used to return multiple rows.
 func multipleRows(items interface{}, keys ...string) [][]interface{} {
 	// Use reflect to get the items.
@@ -105,25 +227,9 @@ func multipleRows(items interface{}, keys ...string) [][]interface{} {
 		// Create the row.
 		row := make([]interface{}, len(keys))
 
-		// Get the value using reflect so we can access fields.
-		outerValue := reflect.Indirect(itemsReflect.Index(i))
-
-		// Go through each key which we want from the field.
-		for i, k := range keys {
-			// Get the locally scoped value.
-			value := outerValue
-
-			// Split by dots so we can get properties.
-			dotsplit := strings.Split(k, ".")
-
-			// Traverse through each field in the key. len-1 is safe here since split
-			// will always return at least 1 item.
-			for x := 0; x < len(dotsplit)-1; x++ {
-				value = reflect.Indirect(value.FieldByName(dotsplit[x]))
-			}
-
-			// Get the item from the struct.
-			row[i] = value.FieldByName(dotsplit[len(dotsplit)-1]).Interface()
+		// Get the value of each key, which can use dots to get properties.
+		for x, k := range keys {
+			row[x] = valueByPath(itemsReflect.Index(i), k)
 		}
 
 		// Add to the array.
@@ -134,6 +240,42 @@ func multipleRows(items interface{}, keys ...string) [][]interface{} {
 	return rows
 }
 
+// Used to format a duration in a human-readable way.
+func humanDuration(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+	d = d.Round(time.Minute)
+
+	parts := make([]string, 0, 3)
+	addPart := func(n time.Duration, unit string) {
+		if n == 0 {
+			return
+		}
+		part := strconv.Itoa(int(n)) + " " + unit
+		if n != 1 {
+			part += "s"
+		}
+		parts = append(parts, part)
+	}
+	days := d / (24 * time.Hour)
+	addPart(days, "day")
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	addPart(hours, "hour")
+	d -= hours * time.Hour
+	addPart(d/time.Minute, "minute")
+	return strings.Join(parts, " ")
+}
+
+// Used to get the time until a timestamp.
+func timeUntil(ts *timestamp.Timestamp) string {
+	if ts == nil {
+		return "unknown"
+	}
+	return humanDuration(ts.Time().Sub(timeNow()))
+}
+
 // Used to render the template.
 func renderTemplate(w io.Writer, tpl string, data interface{}) error {
 	parsed, err := template.New("tpl").Funcs(template.FuncMap{
@@ -142,6 +284,20 @@ func renderTemplate(w io.Writer, tpl string, data interface{}) error {
 		"StringSlice":  stringSlice,
 		"SingleRow":    singleRow,
 		"MultipleRows": multipleRows,
+		"TimeUntil":    timeUntil,
+		"json":         templateJSON,
+		"yaml":         templateYAML,
+		"join":         templateJoin,
+		"upper":        templateUpper,
+		"lower":        templateLower,
+		"default":      templateDefault,
+		"timeAgo":      templateTimeAgo,
+		"date":         templateDate,
+		"color":        templateColor,
+		"truncate":     templateTruncate,
+		"humanBytes":   templateHumanBytes,
+		"pluck":        templatePluck,
+		"where":        templateWhere,
 	}).Parse(tpl)
 	if err != nil {
 		return err
@@ -153,6 +309,69 @@ func renderTemplate(w io.Writer, tpl string, data interface{}) error {
 type genericOutput struct {
 	item                interface{}
 	defaultTextTemplate string
+
+	// Defines the columns when the item is a list. These are used for CSV output, and for text output when there
+	// isn't a default template.
+	columns []*outputColumn
+
+	// Defines the columns for wide output. If this is nil, the columns are used.
+	wideColumns []*outputColumn
+}
+
+// Used to check if the output is wide.
+func isWideOutput() bool {
+	return strings.ToLower(outputFlag) == "wide"
+}
+
+// Used to check if the output is a table of columns. This is csv, tsv and wide output, or text output with
+// --columns.
+func isTableOutput() bool {
+	switch strings.ToLower(outputFlag) {
+	case "csv", "tsv", "wide":
+		return true
+	default:
+		return isTextOutput() && len(columnsFlag) != 0
+	}
+}
+
+// Used to get the type of the items in a list.
+func listItemType(items interface{}) reflect.Type {
+	t := reflect.TypeOf(items)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		return t.Elem()
+	}
+	return t
+}
+
+// Used to get the columns to output. These are the columns set with --columns, the wide columns if the output is
+// wide, or the default columns.
+func (g *genericOutput) outputColumns() ([]*outputColumn, error) {
+	if len(columnsFlag) == 0 {
+		if isWideOutput() && g.wideColumns != nil {
+			return g.wideColumns, nil
+		}
+		return g.columns, nil
+	}
+
+	if g.columns == nil {
+		return nil, errors.New("--columns is not supported by this command")
+	}
+	t := listItemType(g.item)
+	columns := make([]*outputColumn, 0, len(columnsFlag))
+	for _, path := range columnsFlag {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if t == nil || !hasPath(t, path) {
+			return nil, fmt.Errorf("unknown column %s", path)
+		}
+		columns = append(columns, &outputColumn{Header: path, Path: path})
+	}
+	return columns, nil
 }
 
 // JSON is used to write out the JSON output.
@@ -165,6 +384,27 @@ func (g *genericOutput) JSON(w io.Writer) error {
 	return err
 }
 
+// Used to write a value as JSON lines. Each item of a slice is written on its own line, and anything else is
+// written as a single line.
+func writeNDJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	items := reflect.ValueOf(v)
+	if items.Kind() != reflect.Slice {
+		return enc.Encode(v)
+	}
+	for i := 0; i < items.Len(); i++ {
+		if err := enc.Encode(items.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NDJSON is used to write out JSON lines, with each item of a list on its own line.
+func (g *genericOutput) NDJSON(w io.Writer) error {
+	return writeNDJSON(w, g.item)
+}
+
 // YAML is used to write out the YAML output.
 func (g *genericOutput) YAML(w io.Writer) error {
 	b, err := yaml.Marshal(g.item)
@@ -178,6 +418,16 @@ func (g *genericOutput) YAML(w io.Writer) error {
 // Text is used to render a template. If string is blank, uses the default.
 func (g *genericOutput) Text(w io.Writer, template string) error {
 	if template == "" {
+		// Render the columns as a table if there is no default template, or the columns were picked.
+		columns, err := g.outputColumns()
+		if err != nil {
+			return err
+		}
+		if columns != nil && (g.defaultTextTemplate == "" || len(columnsFlag) != 0 || isWideOutput()) {
+			_, err = io.WriteString(w, table(columnRows(g.item, columns)))
+			return err
+		}
+
 		// Return the default template.
 		template = g.defaultTextTemplate
 	}
@@ -186,12 +436,107 @@ func (g *genericOutput) Text(w io.Writer, template string) error {
 	return renderTemplate(w, template, g.item)
 }
 
+// CSV is used to write out the columns as delimited values with a header row.
+func (g *genericOutput) CSV(w io.Writer, comma rune) error {
+	if g.columns == nil {
+		return errors.New("csv and tsv output is not supported by this command")
+	}
+	columns, err := g.outputColumns()
+	if err != nil {
+		return err
+	}
+	headers, rows := columnRows(g.item, columns)
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if !noHeadersFlag {
+		if err = cw.Write(headers); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = tableValue(v)
+		}
+		if err = cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
 // Defines a function that returns a output.
 type outputFunc func(cmd *cobra.Command, args []string) (Output, error)
 
+// Used to check if the output is going to be rendered as text.
+func isTextOutput() bool {
+	switch strings.ToLower(outputFlag) {
+	case "json", "ndjson", "yml", "yaml", "csv", "tsv":
+		return false
+	default:
+		return true
+	}
+}
+
+// Used to transform the lists in the item of an output. The item must either be a slice, or a map of slices in
+// which case each slice is transformed. False is returned if the output isn't a genericOutput with lists.
+func transformLists(
+	output Output, transform func(list reflect.Value) (interface{}, error),
+) (Output, bool, error) {
+	g, ok := output.(*genericOutput)
+	if !ok {
+		return nil, false, nil
+	}
+	item := reflect.ValueOf(g.item)
+	var result interface{}
+	switch {
+	case item.Kind() == reflect.Slice:
+		list, err := transform(item)
+		if err != nil {
+			return nil, false, err
+		}
+		result = list
+	case item.Kind() == reflect.Map && item.Type().Key().Kind() == reflect.String:
+		m := reflect.MakeMapWithSize(item.Type(), item.Len())
+		iter := item.MapRange()
+		for iter.Next() {
+			v := iter.Value()
+			for v.Kind() == reflect.Interface {
+				v = v.Elem()
+			}
+			if v.Kind() != reflect.Slice {
+				m.SetMapIndex(iter.Key(), iter.Value())
+				continue
+			}
+			list, err := transform(v)
+			if err != nil {
+				return nil, false, err
+			}
+			m.SetMapIndex(iter.Key(), reflect.ValueOf(list))
+			result = m.Interface()
+		}
+	}
+	if result == nil {
+		return nil, false, nil
+	}
+	return &genericOutput{
+		item:                result,
+		defaultTextTemplate: g.defaultTextTemplate,
+		columns:             g.columns,
+		wideColumns:         g.wideColumns,
+	}, true, nil
+}
+
 // Used to render a console output of a type. Passes through errors.
 func outputWrapper(f outputFunc) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		// Check the paging flags before doing any work.
+		if err := validatePagingFlags(); err != nil {
+			return err
+		}
+
 		// Get stdout.
 		out := cmd.OutOrStdout()
 
@@ -201,14 +546,55 @@ func outputWrapper(f outputFunc) func(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
+		// Collect the pages of the list unless it can be streamed. Filters, sorting and queries need the whole
+		// list, and the limit is applied after filtering and sorting so it picks from the result.
+		listFlagsSet := len(filterFlags) != 0 || sortByFlag != "" || reverseFlag
+		p, paged := output.(*pagedOutput)
+		if paged && (strings.ToLower(outputFlag) != "ndjson" || listFlagsSet || queryFlag != "") {
+			if output, err = p.collect(!listFlagsSet); err != nil {
+				return err
+			}
+		}
+
+		// Filter and sort the output if any of the flags were set.
+		if listFlagsSet {
+			if output, err = applyFilterAndSort(output); err != nil {
+				return err
+			}
+		}
+
+		// Page through lists which the API doesn't page, or apply the limit which was held back above.
+		if pagingFlagsSet() && (!paged || listFlagsSet) {
+			if output, err = applyPaging(output, !paged); err != nil {
+				return err
+			}
+		}
+
+		// Run the query over the output if one was set.
+		if queryFlag != "" {
+			if output, err = applyQuery(output, queryFlag); err != nil {
+				return err
+			}
+		}
+
 		// Handle calling the correct render function.
 		switch strings.ToLower(outputFlag) {
 		case "json":
 			return output.JSON(out)
+		case "ndjson":
+			return output.NDJSON(out)
 		case "yml", "yaml":
 			return output.YAML(out)
+		case "csv":
+			return output.CSV(out, ',')
+		case "tsv":
+			return output.CSV(out, '\t')
 		default:
-			return output.Text(out, templateFlag)
+			tpl, err := loadTemplate(templateFlag)
+			if err != nil {
+				return err
+			}
+			return output.Text(out, tpl)
 		}
 	}
 }
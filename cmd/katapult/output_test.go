@@ -149,6 +149,59 @@ func Test_genericOutput_Text(t *testing.T) {
 	}
 }
 
+// Defines a row in the CSV tests.
+type csvTestRow struct {
+	Name  string
+	Inner *struct{ Value int }
+}
+
+func Test_genericOutput_CSV(t *testing.T) {
+	rows := []*csvTestRow{
+		{Name: "hello", Inner: &struct{ Value int }{Value: 1}},
+		{Name: "hello, \"world\"", Inner: &struct{ Value int }{Value: 2}},
+	}
+	columns := []*outputColumn{{Header: "Name", Path: "Name"}, {Header: "Value", Path: "Inner.Value"}}
+	tests := []struct {
+		name string
+
+		columns []*outputColumn
+		comma   rune
+		want    string
+		wantErr string
+	}{
+		{
+			name:    "csv",
+			columns: columns,
+			comma:   ',',
+			want:    "Name,Value\nhello,1\n\"hello, \"\"world\"\"\",2\n",
+		},
+		{
+			name:    "tsv",
+			columns: columns,
+			comma:   '\t',
+			want:    "Name\tValue\nhello\t1\n\"hello, \"\"world\"\"\"\t2\n",
+		},
+		{
+			name:    "no columns",
+			comma:   ',',
+			wantErr: "csv and tsv output is not supported by this command",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &genericOutput{item: rows, columns: tt.columns}
+			buf := &bytes.Buffer{}
+			err := g.CSV(buf, tt.comma)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
 func Test_outputWrapper(t *testing.T) {
 	tests := []struct {
 		name string
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+
+	"github.com/krystal/go-katapult"
+	"github.com/krystal/go-katapult/core"
+)
+
+// Defines the flags used to page through lists.
+var (
+	limitFlag    int
+	pageFlag     int
+	pageSizeFlag int
+	parallelFlag = 4
+)
+
+// Used to check if --limit, --page or --page-size were set.
+func pagingFlagsSet() bool {
+	return limitFlag != 0 || pageFlag != 0 || pageSizeFlag != 0
+}
+
+// Used to validate the paging flags.
+func validatePagingFlags() error {
+	switch {
+	case limitFlag < 0:
+		return errors.New("--limit cannot be negative")
+	case pageFlag < 0:
+		return errors.New("--page cannot be negative")
+	case pageSizeFlag < 0:
+		return errors.New("--page-size cannot be negative")
+	case parallelFlag < 1:
+		return errors.New("--parallel must be at least 1")
+	default:
+		return nil
+	}
+}
+
+// Used to fetch a page of a list. The items must be a slice.
+type pageFetcher func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error)
+
+// Defines how a list is paged through.
+type paginator struct {
+	// Defines the page to fetch. If this is 0, every page is fetched.
+	page int
+
+	// Defines the number of items on each page. If this is 0, the API default is used.
+	perPage int
+
+	// Defines the most items to return. If this is 0, there is no limit.
+	limit int
+
+	// Defines how many pages are fetched at once.
+	parallel int
+}
+
+// Used to get the paginator set by the flags.
+func flagPaginator() *paginator {
+	return &paginator{page: pageFlag, perPage: pageSizeFlag, limit: limitFlag, parallel: parallelFlag}
+}
+
+// Returned by onPage when the limit has been reached to stop fetching pages.
+var errLimitReached = errors.New("limit reached")
+
+// Used to call onPage with the items of each page in order. Once the limit is reached, the last page is cut short
+// and no more pages are fetched.
+func (p *paginator) each(ctx context.Context, fetch pageFetcher, onPage func(items interface{}) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Wrap onPage to apply the limit.
+	remaining := p.limit
+	handle := func(items interface{}) error {
+		if p.limit == 0 {
+			return onPage(items)
+		}
+		v := reflect.ValueOf(items)
+		if v.Len() < remaining {
+			remaining -= v.Len()
+			return onPage(items)
+		}
+		if err := onPage(v.Slice(0, remaining).Interface()); err != nil {
+			return err
+		}
+		return errLimitReached
+	}
+
+	var err error
+	if p.page == 0 {
+		err = p.fetchAll(ctx, fetch, handle)
+	} else {
+		var items interface{}
+		items, _, err = fetch(ctx, &core.ListOptions{Page: p.page, PerPage: p.perPage})
+		if err == nil {
+			err = handle(items)
+		}
+	}
+	if errors.Is(err, errLimitReached) {
+		return nil
+	}
+	return err
+}
+
+// Defines the result of fetching a page.
+type pageResult struct {
+	items interface{}
+	err   error
+}
+
+// Used to fetch every page of a list. The first page is fetched on its own to get the total number of pages, and
+// then the rest are fetched with up to p.parallel requests at once. onPage is called with the items of each page
+// in order as soon as they are available. No more than p.parallel pages are held at once, so a slow onPage slows
+// down fetching rather than using more memory.
+func (p *paginator) fetchAll(ctx context.Context, fetch pageFetcher, onPage func(items interface{}) error) error {
+	items, resp, err := fetch(ctx, &core.ListOptions{Page: 1, PerPage: p.perPage})
+	if err != nil {
+		return err
+	}
+	if err = onPage(items); err != nil {
+		return err
+	}
+	totalPages := 1
+	if resp != nil && resp.Pagination != nil {
+		totalPages = resp.Pagination.TotalPages
+	}
+	if totalPages <= 1 {
+		return nil
+	}
+
+	// Cancel any requests which are still running if we return early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Start fetching the pages. Each page has its own channel so they can be handled in order. A slot is taken
+	// for each page and given back once the page has been handled.
+	results := make([]chan pageResult, totalPages+1)
+	for page := 2; page <= totalPages; page++ {
+		results[page] = make(chan pageResult, 1)
+	}
+	slots := make(chan struct{}, p.parallel)
+	go func() {
+		for page := 2; page <= totalPages; page++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				results[page] <- pageResult{err: ctx.Err()}
+				continue
+			}
+			go func(page int) {
+				items, _, err := fetch(ctx, &core.ListOptions{Page: page, PerPage: p.perPage})
+				results[page] <- pageResult{items: items, err: err}
+			}(page)
+		}
+	}()
+
+	// Handle the pages in order.
+	for page := 2; page <= totalPages; page++ {
+		var result pageResult
+		select {
+		case result = <-results[page]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if result.err != nil {
+			return result.err
+		}
+		if err = onPage(result.items); err != nil {
+			return err
+		}
+		<-slots
+	}
+	return nil
+}
+
+// Used to fetch every page of a list into the slice that out points to. This ignores the paging flags since it
+// is used to get everything that can be picked from.
+func listAllPages(ctx context.Context, fetch pageFetcher, out interface{}) error {
+	all := reflect.ValueOf(out).Elem()
+	p := &paginator{parallel: parallelFlag}
+	return p.each(ctx, fetch, func(items interface{}) error {
+		all.Set(reflect.AppendSlice(all, reflect.ValueOf(items)))
+		return nil
+	})
+}
+
+// Used to apply --page, --page-size and --limit to a list which was not paged by the API. If there isn't a page
+// size, the whole list is treated as one page.
+func pageList(list reflect.Value, clientPages bool) interface{} {
+	if clientPages && pageFlag > 1 {
+		start := list.Len()
+		if pageSizeFlag != 0 && (pageFlag-1)*pageSizeFlag < start {
+			start = (pageFlag - 1) * pageSizeFlag
+		}
+		list = list.Slice(start, list.Len())
+	}
+	if clientPages && pageSizeFlag != 0 && pageFlag != 0 && list.Len() > pageSizeFlag {
+		list = list.Slice(0, pageSizeFlag)
+	}
+	if limitFlag != 0 && list.Len() > limitFlag {
+		list = list.Slice(0, limitFlag)
+	}
+	return list.Interface()
+}
+
+// Used to apply the paging flags to the lists of an output. If clientPages is false, the pages have already been
+// fetched from the API and only --limit is applied.
+func applyPaging(output Output, clientPages bool) (Output, error) {
+	result, ok, err := transformLists(output, func(list reflect.Value) (interface{}, error) {
+		return pageList(list, clientPages), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("--limit, --page and --page-size are only supported by list commands")
+	}
+	return result, nil
+}
+
+// Used to output a list which is fetched a page at a time. For ndjson output, the items are written as soon as
+// their page arrives. Everything else needs the whole list, so the pages are collected into a genericOutput.
+type pagedOutput struct {
+	ctx         context.Context
+	fetch       pageFetcher
+	columns     []*outputColumn
+	wideColumns []*outputColumn
+}
+
+// Used to fetch the pages set by the flags and collect the items into a genericOutput. If limit is false, --limit
+// is not applied so it can be applied after filtering and sorting.
+func (p *pagedOutput) collect(limit bool) (*genericOutput, error) {
+	pages := flagPaginator()
+	if !limit {
+		pages.limit = 0
+	}
+	var all reflect.Value
+	err := pages.each(p.ctx, p.fetch, func(items interface{}) error {
+		v := reflect.ValueOf(items)
+		if !all.IsValid() {
+			all = reflect.MakeSlice(v.Type(), 0, v.Len())
+		}
+		all = reflect.AppendSlice(all, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &genericOutput{
+		item:        all.Interface(),
+		columns:     p.columns,
+		wideColumns: p.wideColumns,
+	}, nil
+}
+
+// JSON is used to write out the JSON output.
+func (p *pagedOutput) JSON(w io.Writer) error {
+	g, err := p.collect(true)
+	if err != nil {
+		return err
+	}
+	return g.JSON(w)
+}
+
+// YAML is used to write out the YAML output.
+func (p *pagedOutput) YAML(w io.Writer) error {
+	g, err := p.collect(true)
+	if err != nil {
+		return err
+	}
+	return g.YAML(w)
+}
+
+// Text is used to render a template. If string is blank, uses the default.
+func (p *pagedOutput) Text(w io.Writer, template string) error {
+	g, err := p.collect(true)
+	if err != nil {
+		return err
+	}
+	return g.Text(w, template)
+}
+
+// CSV is used to write out the columns as delimited values with a header row.
+func (p *pagedOutput) CSV(w io.Writer, comma rune) error {
+	g, err := p.collect(true)
+	if err != nil {
+		return err
+	}
+	return g.CSV(w, comma)
+}
+
+// NDJSON is used to write out each item on its own line as soon as its page arrives.
+func (p *pagedOutput) NDJSON(w io.Writer) error {
+	return flagPaginator().each(p.ctx, p.fetch, func(items interface{}) error {
+		return writeNDJSON(w, items)
+	})
+}
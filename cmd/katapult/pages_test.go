@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/krystal/go-katapult"
+	"github.com/krystal/go-katapult/core"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Used to fetch pages of numbers. Each page has perPage numbers (2 by default), counting up from 1. It tracks
+// which pages were fetched and how many were fetched at once.
+type mockPages struct {
+	totalPages int
+	failPage   int
+
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+	fetched  []int
+	perPage  []int
+}
+
+func (m *mockPages) fetch(_ context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+	m.mu.Lock()
+	m.inFlight++
+	if m.inFlight > m.maxSeen {
+		m.maxSeen = m.inFlight
+	}
+	m.fetched = append(m.fetched, opts.Page)
+	m.perPage = append(m.perPage, opts.PerPage)
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.inFlight--
+		m.mu.Unlock()
+	}()
+
+	if opts.Page == m.failPage {
+		return nil, nil, errors.New("page " + strconv.Itoa(opts.Page) + " failed")
+	}
+	size := opts.PerPage
+	if size == 0 {
+		size = 2
+	}
+	items := make([]int, size)
+	for i := range items {
+		items[i] = (opts.Page-1)*size + i + 1
+	}
+	return items, &katapult.Response{Pagination: &katapult.Pagination{
+		CurrentPage: opts.Page,
+		TotalPages:  m.totalPages,
+		PerPage:     size,
+	}}, nil
+}
+
+func Test_paginator_each(t *testing.T) {
+	tests := []struct {
+		name string
+
+		totalPages int
+		failPage   int
+		paginator  paginator
+		want       []int
+		wantPages  int
+		wantErr    string
+	}{
+		{
+			name:       "single page",
+			totalPages: 1,
+			paginator:  paginator{parallel: 4},
+			want:       []int{1, 2},
+			wantPages:  1,
+		},
+		{
+			name:       "many pages",
+			totalPages: 10,
+			paginator:  paginator{parallel: 4},
+			want:       []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20},
+			wantPages:  10,
+		},
+		{
+			name:       "sequential",
+			totalPages: 5,
+			paginator:  paginator{parallel: 1},
+			want:       []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			wantPages:  5,
+		},
+		{
+			name:       "page size",
+			totalPages: 2,
+			paginator:  paginator{perPage: 3, parallel: 4},
+			want:       []int{1, 2, 3, 4, 5, 6},
+			wantPages:  2,
+		},
+		{
+			name:       "limit",
+			totalPages: 10,
+			paginator:  paginator{limit: 5, parallel: 1},
+			want:       []int{1, 2, 3, 4, 5},
+			wantPages:  3,
+		},
+		{
+			name:       "limit on a page boundary",
+			totalPages: 10,
+			paginator:  paginator{limit: 4, parallel: 1},
+			want:       []int{1, 2, 3, 4},
+			wantPages:  2,
+		},
+		{
+			name:       "single page by number",
+			totalPages: 10,
+			paginator:  paginator{page: 3, perPage: 5, parallel: 4},
+			want:       []int{11, 12, 13, 14, 15},
+			wantPages:  1,
+		},
+		{
+			name:       "single page with a limit",
+			totalPages: 10,
+			paginator:  paginator{page: 2, limit: 1, parallel: 4},
+			want:       []int{3},
+			wantPages:  1,
+		},
+		{
+			name:       "first page fails",
+			totalPages: 5,
+			failPage:   1,
+			paginator:  paginator{parallel: 4},
+			wantPages:  1,
+			wantErr:    "page 1 failed",
+		},
+		{
+			name:       "later page fails",
+			totalPages: 6,
+			failPage:   4,
+			paginator:  paginator{parallel: 1},
+			want:       []int{1, 2, 3, 4, 5, 6},
+			wantPages:  4,
+			wantErr:    "page 4 failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &mockPages{totalPages: tt.totalPages, failPage: tt.failPage}
+			var got []int
+			err := tt.paginator.each(context.Background(), m.fetch, func(items interface{}) error {
+				got = append(got, items.([]int)...)
+				return nil
+			})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.LessOrEqual(t, m.maxSeen, tt.paginator.parallel)
+			for _, perPage := range m.perPage {
+				assert.Equal(t, tt.paginator.perPage, perPage)
+			}
+
+			// Sequential fetching stops straight away, but parallel fetching may have started more pages.
+			if tt.paginator.parallel == 1 || tt.wantPages == 1 {
+				assert.Len(t, m.fetched, tt.wantPages)
+			}
+		})
+	}
+}
+
+func Test_paginator_each_StopsOnError(t *testing.T) {
+	m := &mockPages{totalPages: 100}
+	err := (&paginator{parallel: 4}).each(context.Background(), m.fetch, func(items interface{}) error {
+		if items.([]int)[0] == 3 {
+			return errors.New("write failed")
+		}
+		return nil
+	})
+	require.EqualError(t, err, "write failed")
+
+	// Only the pages which had a slot can have been fetched.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	assert.LessOrEqual(t, len(m.fetched), 2+4)
+}
+
+func Test_paginator_each_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &mockPages{totalPages: 10}
+	fetch := func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+		if opts.Page == 1 {
+			return m.fetch(ctx, opts)
+		}
+
+		// Block the rest of the pages until the context is cancelled.
+		<-ctx.Done()
+		return nil, nil, ctx.Err()
+	}
+	var got []int
+	err := (&paginator{parallel: 2}).each(ctx, fetch, func(items interface{}) error {
+		got = append(got, items.([]int)...)
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, []int{1, 2}, got)
+
+	// A cancelled context doesn't fetch anything.
+	m = &mockPages{totalPages: 10}
+	err = (&paginator{parallel: 2}).each(ctx, m.fetch, func(items interface{}) error {
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, m.fetched)
+}
+
+func Test_pagedOutput_NDJSON(t *testing.T) {
+	// Each page after the first is only returned once the page before it has been written, so this would
+	// deadlock if the items were not written as soon as their page arrived.
+	buf := &bytes.Buffer{}
+	var mu sync.Mutex
+	written := map[int]chan struct{}{}
+	for i := 1; i <= 3; i++ {
+		written[i] = make(chan struct{})
+	}
+	m := &mockPages{totalPages: 3}
+	p := &pagedOutput{
+		ctx: context.Background(),
+		fetch: func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+			if opts.Page > 1 {
+				<-written[opts.Page-1]
+			}
+			opts.PerPage = 1
+			return m.fetch(ctx, opts)
+		},
+	}
+	w := writerFunc(func(b []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		n, err := buf.Write(b)
+		page, _ := strconv.Atoi(string(bytes.TrimSpace(b)))
+		close(written[page])
+		return n, err
+	})
+	require.NoError(t, p.NDJSON(w))
+	assert.Equal(t, "1\n2\n3\n", buf.String())
+}
+
+// Used to make a function an io.Writer.
+type writerFunc func(b []byte) (int, error)
+
+func (f writerFunc) Write(b []byte) (int, error) {
+	return f(b)
+}
+
+func TestPaging(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args     []string
+		limit    int
+		page     int
+		pageSize int
+		parallel int
+		filters  []string
+		output   string
+		wantErr  string
+	}{
+		{
+			name:  "vm list limit",
+			args:  []string{"vm", "list", "loge"},
+			limit: 3,
+		},
+		{
+			name: "vm list page",
+			args: []string{"vm", "list", "loge"},
+			page: 2,
+		},
+		{
+			name:    "vm list limit after filter",
+			args:    []string{"vm", "list", "loge"},
+			limit:   2,
+			filters: []string{"state=stopped"},
+		},
+		{
+			name:   "vm list limit ndjson",
+			args:   []string{"vm", "list", "loge"},
+			limit:  3,
+			output: "ndjson",
+		},
+		{
+			name:  "dc list limit",
+			args:  []string{"dc", "list"},
+			limit: 1,
+		},
+		{
+			name:     "dc list page",
+			args:     []string{"dc", "list"},
+			page:     2,
+			pageSize: 1,
+		},
+		{
+			name: "dc list page without a page size",
+			args: []string{"dc", "list"},
+			page: 2,
+		},
+		{
+			name:    "not a list",
+			args:    []string{"dc", "get", "POG1"},
+			limit:   1,
+			wantErr: "--limit, --page and --page-size are only supported by list commands",
+		},
+		{
+			name:    "negative limit",
+			args:    []string{"dc", "list"},
+			limit:   -1,
+			wantErr: "--limit cannot be negative",
+		},
+		{
+			name:     "no parallel requests",
+			args:     []string{"dc", "list"},
+			parallel: -1,
+			wantErr:  "--parallel must be at least 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vms := make([]*core.VirtualMachine, 5)
+			for i := range vms {
+				vms[i] = &core.VirtualMachine{ID: "vm_" + strconv.Itoa(i+1), Name: "VM " + strconv.Itoa(i+1)}
+				if i%2 == 0 {
+					vms[i].State = core.VirtualMachineStopped
+				}
+			}
+			cmd := &cobra.Command{}
+			cmd.AddCommand(
+				virtualMachinesCmd(&vmsClient{organizationSubdomainPages: map[string]vmPages{
+					"loge": {vms[:2], vms[2:4], vms[4:]},
+				}}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil),
+				dataCentersCmd(mockDataCentersClient{dcs: fixtureDataCenters}),
+			)
+			cmd.SetArgs(tt.args)
+			limitFlag, pageFlag, pageSizeFlag = tt.limit, tt.page, tt.pageSize
+			filterFlags = tt.filters
+			outputFlag = tt.output
+			if tt.parallel != 0 {
+				parallelFlag = tt.parallel
+			}
+			defer func() {
+				limitFlag, pageFlag, pageSizeFlag, parallelFlag = 0, 0, 0, 4
+				filterFlags = nil
+				outputFlag = ""
+			}()
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+		})
+	}
+}
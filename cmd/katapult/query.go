@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/jmespath/go-jmespath"
+	"gopkg.in/yaml.v3"
+)
+
+// Defines the JMESPath expression set with --query.
+var queryFlag string
+
+// Used to output the result of a query. The result is made up of JSON values, so the fields use the same names
+// as the JSON output.
+type queryOutput struct {
+	result interface{}
+}
+
+// Used to run the query set with --query over the item of an output.
+func applyQuery(output Output, query string) (Output, error) {
+	g, ok := output.(*genericOutput)
+	if !ok {
+		return nil, errors.New("--query is not supported by this command")
+	}
+	expr, err := jmespath.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	// Convert the item to JSON values so the query uses the same names as the JSON output.
+	b, err := json.Marshal(g.item)
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err = json.Unmarshal(b, &data); err != nil {
+		return nil, err
+	}
+
+	result, err := expr.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the query: %w", err)
+	}
+	return &queryOutput{result: result}, nil
+}
+
+// Used to check if a JSON value is a scalar.
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
+
+// Used to get the columns of a list of objects. These are the columns set with --columns, or all of the keys
+// of the objects. False is returned if the result is not a list of objects.
+func (q *queryOutput) objectColumns() ([]string, []interface{}, bool) {
+	items, ok := q.result.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, nil, false
+	}
+	keys := map[string]bool{}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	if len(columnsFlag) != 0 {
+		return columnsFlag, items, true
+	}
+	columns := make([]string, 0, len(keys))
+	for k := range keys {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+	return columns, items, true
+}
+
+// JSON is used to write out the JSON output.
+func (q *queryOutput) JSON(w io.Writer) error {
+	return (&genericOutput{item: q.result}).JSON(w)
+}
+
+// NDJSON is used to write out JSON lines. If the result is a list, each item is written on its own line.
+func (q *queryOutput) NDJSON(w io.Writer) error {
+	return writeNDJSON(w, q.result)
+}
+
+// YAML is used to write out the YAML output.
+func (q *queryOutput) YAML(w io.Writer) error {
+	b, err := yaml.Marshal(q.result)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// Text is used to render the result. Scalars are written on their own line, lists of scalars are written one per
+// line, and lists of objects are written as a table. Anything else is written as JSON.
+func (q *queryOutput) Text(w io.Writer, template string) error {
+	if template != "" {
+		return renderTemplate(w, template, q.result)
+	}
+
+	if columns, items, ok := q.objectColumns(); ok {
+		_, err := io.WriteString(w, table(columns, multipleRows(items, columns...)))
+		return err
+	}
+
+	switch x := q.result.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, v := range x {
+			if !isScalar(v) {
+				return q.textJSON(w)
+			}
+		}
+		for _, v := range x {
+			if _, err := fmt.Fprintln(w, tableValue(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		return q.textJSON(w)
+	default:
+		_, err := fmt.Fprintln(w, tableValue(x))
+		return err
+	}
+}
+
+// Used to write the result as JSON in text output.
+func (q *queryOutput) textJSON(w io.Writer) error {
+	if err := q.JSON(w); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// CSV is used to write out the result as delimited values. The result must be a list of objects or scalars.
+func (q *queryOutput) CSV(w io.Writer, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	write := func(headers []string, rows [][]interface{}) error {
+		if !noHeadersFlag {
+			if err := cw.Write(headers); err != nil {
+				return err
+			}
+		}
+		for _, row := range rows {
+			record := make([]string, len(row))
+			for i, v := range row {
+				record[i] = tableValue(v)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	if columns, items, ok := q.objectColumns(); ok {
+		return write(columns, multipleRows(items, columns...))
+	}
+	if items, ok := q.result.([]interface{}); ok {
+		rows := make([][]interface{}, len(items))
+		for i, v := range items {
+			if !isScalar(v) {
+				return errors.New("the query result must be a list of objects or values for csv and tsv output")
+			}
+			rows[i] = []interface{}{v}
+		}
+		return write([]string{"value"}, rows)
+	}
+	if _, ok := q.result.(map[string]interface{}); ok || q.result == nil {
+		return errors.New("the query result must be a list of objects or values for csv and tsv output")
+	}
+	return write([]string{"value"}, [][]interface{}{{q.result}})
+}
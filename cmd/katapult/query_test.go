@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_queryOutput(t *testing.T) {
+	assert.Implements(t, (*Output)(nil), &queryOutput{})
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		name string
+
+		query   string
+		output  string
+		columns []string
+		wantErr string
+	}{
+		{
+			name:  "list of values",
+			query: "[?state=='started'].fqdn",
+		},
+		{
+			name:  "single value",
+			query: "[0].package.name",
+		},
+		{
+			name:  "list of objects",
+			query: "[].{name: name, package: package.name}",
+		},
+		{
+			name:    "list of objects with columns",
+			query:   "[].{name: name, package: package.name, zone: zone.name}",
+			columns: []string{"zone", "name"},
+		},
+		{
+			name:  "object",
+			query: "[0].zone",
+		},
+		{
+			name:   "json output",
+			query:  "[].{id: id, created_at: created_at}",
+			output: "json",
+		},
+		{
+			name:   "yaml output",
+			query:  "[].name",
+			output: "yaml",
+		},
+		{
+			name:   "ndjson output",
+			query:  "[].{name: name, state: state}",
+			output: "ndjson",
+		},
+		{
+			name:   "csv output",
+			query:  "[].{name: name, created_at: created_at}",
+			output: "csv",
+		},
+		{
+			name:   "csv list of values",
+			query:  "[].id",
+			output: "csv",
+		},
+		{
+			name:    "csv object",
+			query:   "[0]",
+			output:  "csv",
+			wantErr: "the query result must be a list of objects or values for csv and tsv output",
+		},
+		{
+			name:    "invalid query",
+			query:   "[?state==",
+			wantErr: "invalid query: SyntaxError: Incomplete expression",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := map[string]vmPages{"loge": {{fixtureVirtualMachine, {ID: "vm_2", Name: "Empty"}}}}
+			cmd := virtualMachinesCmd(
+				&vmsClient{organizationSubdomainPages: pages}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd.SetArgs([]string{"list", "loge"})
+			queryFlag = tt.query
+			outputFlag = tt.output
+			columnsFlag = tt.columns
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			queryFlag = ""
+			outputFlag = ""
+			columnsFlag = nil
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Defines the defaults for retrying requests.
+const (
+	defaultRetries      = 3
+	defaultRetryMaxWait = 30 * time.Second
+	retryBaseWait       = 500 * time.Millisecond
+)
+
+// Defines the methods which are safe to retry after a server error, since sending them twice has the same effect
+// as sending them once.
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// Defines the server errors which are worth retrying.
+var transientStatuses = map[int]bool{
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// Used to retry requests which were rate limited or failed with a transient error. Rate limited requests (429)
+// were not handled by the API, so they are retried for every method. Server errors, network errors and attempts
+// which time out are only retried for idempotent methods, since the API may have already acted on the others.
+type retryTransport struct {
+	next http.RoundTripper
+
+	// Defines how many times a request is retried.
+	retries int
+
+	// Defines the longest time to wait between attempts. If the API asks us to wait longer than this with
+	// Retry-After, the response is returned rather than retried.
+	maxWait time.Duration
+
+	// Defines the longest time each attempt can take, including reading the response body. The time spent
+	// waiting between attempts doesn't count towards this. Zero means there is no limit.
+	timeout time.Duration
+
+	// Used to wait between attempts. This is mocked in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Used to create a retry transport.
+func newRetryTransport(next http.RoundTripper, retries int, maxWait, timeout time.Duration) *retryTransport {
+	return &retryTransport{next: next, retries: retries, maxWait: maxWait, timeout: timeout, sleep: sleepContext}
+}
+
+// Used to cancel the context of an attempt once its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// Used to wait for a duration, stopping early if the context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Used to get how long to wait before an attempt. This doubles with each attempt up to the max wait, and then a
+// random amount of up to half is taken off so that clients which failed together don't retry together.
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	wait := t.maxWait
+	if attempt < 32 && retryBaseWait<<uint(attempt) < wait {
+		wait = retryBaseWait << uint(attempt)
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait - time.Duration(rand.Int63n(int64(wait/2)+1)) //nolint:gosec
+}
+
+// Used to get the wait from a Retry-After header, which is either a number of seconds or a date. False is
+// returned if the header isn't set or can't be parsed.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if date, err := http.ParseTime(v); err == nil {
+		wait := date.Sub(timeNow())
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// Used to check if a request should be retried after an attempt.
+func (t *retryTransport) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		// The body can't be sent again.
+		return false
+	}
+	if err != nil {
+		return req.Context().Err() == nil && !errors.Is(err, errNotRecorded) && idempotentMethods[req.Method]
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return transientStatuses[resp.StatusCode] && idempotentMethods[req.Method]
+}
+
+// RoundTrip is used to send the request, retrying it if needed.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		// Each attempt has its own timeout, which lasts until its response body is closed.
+		var attemptCtx context.Context
+		var cancel context.CancelFunc
+		if t.timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, t.timeout)
+		} else {
+			attemptCtx, cancel = context.WithCancel(ctx)
+		}
+		attemptReq := req.WithContext(attemptCtx)
+
+		// Each attempt after the first needs a new copy of the body.
+		if attempt != 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			attemptReq.Body = body
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+		if err != nil {
+			cancel()
+		} else {
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		}
+		if attempt >= t.retries || !t.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		// Work out how long to wait. If the API wants us to wait longer than we are willing to, give up.
+		wait := t.backoff(attempt)
+		if resp != nil {
+			if after, ok := retryAfter(resp); ok {
+				if after > t.maxWait {
+					return resp, nil
+				}
+				wait = after
+			}
+
+			// Drain the body so the connection can be reused.
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+			_ = resp.Body.Close()
+		}
+		if err = t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Defines a response from the stub transport. If err is set, it is returned instead of a response. If hang is
+// set, nothing is returned until the request is cancelled.
+type stubResponse struct {
+	status     int
+	retryAfter string
+	err        error
+	hang       bool
+}
+
+// Used to return the responses in order and record the bodies of the requests.
+type stubTransport struct {
+	responses []stubResponse
+	bodies    []string
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ""
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = string(b)
+	}
+	r := s.responses[len(s.bodies)]
+	s.bodies = append(s.bodies, body)
+	if r.hang {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	resp := &http.Response{
+		StatusCode: r.status,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(strings.NewReader("body")),
+		Request:    req,
+	}
+	if r.retryAfter != "" {
+		resp.Header.Set("Retry-After", r.retryAfter)
+	}
+	return resp, nil
+}
+
+func Test_retryTransport(t *testing.T) {
+	tests := []struct {
+		name string
+
+		method     string
+		body       string
+		responses  []stubResponse
+		wantStatus int
+		wantErr    string
+		wantTries  int
+		wantWaits  []time.Duration
+	}{
+		{
+			name:       "success",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 200}},
+			wantStatus: 200,
+			wantTries:  1,
+		},
+		{
+			name:       "rate limited",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 429}, {status: 200}},
+			wantStatus: 200,
+			wantTries:  2,
+		},
+		{
+			name:       "rate limited post",
+			method:     http.MethodPost,
+			body:       `{"name":"test"}`,
+			responses:  []stubResponse{{status: 429}, {status: 429}, {status: 201}},
+			wantStatus: 201,
+			wantTries:  3,
+		},
+		{
+			name:       "server error",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 503}, {status: 502}, {status: 200}},
+			wantStatus: 200,
+			wantTries:  3,
+		},
+		{
+			name:       "server error post",
+			method:     http.MethodPost,
+			body:       `{"name":"test"}`,
+			responses:  []stubResponse{{status: 503}},
+			wantStatus: 503,
+			wantTries:  1,
+		},
+		{
+			name:       "client error",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 404}},
+			wantStatus: 404,
+			wantTries:  1,
+		},
+		{
+			name:       "out of retries",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 500}, {status: 500}, {status: 500}, {status: 500}},
+			wantStatus: 500,
+			wantTries:  4,
+		},
+		{
+			name:      "network error",
+			method:    http.MethodDelete,
+			responses: []stubResponse{{err: errors.New("connection reset")}, {status: 200}},
+			wantTries: 2,
+		},
+		{
+			name:      "network error post",
+			method:    http.MethodPost,
+			responses: []stubResponse{{err: errors.New("connection reset")}},
+			wantErr:   "connection reset",
+			wantTries: 1,
+		},
+		{
+			name:      "not recorded",
+			method:    http.MethodGet,
+			responses: []stubResponse{{err: fmt.Errorf("%w for GET /core/v1/test", errNotRecorded)}},
+			wantErr:   "no recorded response for GET /core/v1/test",
+			wantTries: 1,
+		},
+		{
+			name:       "retry after seconds",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 429, retryAfter: "5"}, {status: 200}},
+			wantStatus: 200,
+			wantTries:  2,
+			wantWaits:  []time.Duration{5 * time.Second},
+		},
+		{
+			name:   "retry after date",
+			method: http.MethodGet,
+			responses: []stubResponse{
+				{status: 503, retryAfter: mockNow.Add(7 * time.Second).Format(http.TimeFormat)},
+				{status: 200},
+			},
+			wantStatus: 200,
+			wantTries:  2,
+			wantWaits:  []time.Duration{7 * time.Second},
+		},
+		{
+			name:       "retry after too long",
+			method:     http.MethodGet,
+			responses:  []stubResponse{{status: 429, retryAfter: "120"}},
+			wantStatus: 429,
+			wantTries:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeNow = func() time.Time { return mockNow }
+			defer func() { timeNow = time.Now }()
+
+			stub := &stubTransport{responses: tt.responses}
+			var waits []time.Duration
+			rt := newRetryTransport(stub, 3, 30*time.Second, 0)
+			rt.sleep = func(_ context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+
+			var body *bytes.Reader
+			if tt.body != "" {
+				body = bytes.NewReader([]byte(tt.body))
+			}
+			var req *http.Request
+			var err error
+			if body == nil {
+				req, err = http.NewRequest(tt.method, "https://api.katapult.io/core/v1/test", nil)
+			} else {
+				req, err = http.NewRequest(tt.method, "https://api.katapult.io/core/v1/test", body)
+			}
+			require.NoError(t, err)
+
+			resp, err := rt.RoundTrip(req)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				if tt.wantStatus != 0 {
+					assert.Equal(t, tt.wantStatus, resp.StatusCode)
+				}
+			}
+			assert.Len(t, stub.bodies, tt.wantTries)
+			for _, b := range stub.bodies {
+				assert.Equal(t, tt.body, b)
+			}
+			assert.Len(t, waits, tt.wantTries-1)
+			if tt.wantWaits != nil {
+				assert.Equal(t, tt.wantWaits, waits)
+			}
+			for _, wait := range waits {
+				assert.LessOrEqual(t, int64(wait), int64(30*time.Second))
+			}
+		})
+	}
+}
+
+func Test_retryTransport_backoff(t *testing.T) {
+	rt := newRetryTransport(nil, 10, 5*time.Second, 0)
+	for attempt, max := range []time.Duration{
+		500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			wait := rt.backoff(attempt)
+			assert.GreaterOrEqual(t, int64(wait), int64(max/2))
+			assert.LessOrEqual(t, int64(wait), int64(max))
+		}
+	}
+	assert.LessOrEqual(t, int64(rt.backoff(100)), int64(5*time.Second))
+}
+
+func Test_retryTransport_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.katapult.io/core/v1/test", nil)
+	require.NoError(t, err)
+
+	stub := &stubTransport{responses: []stubResponse{{status: 503}, {status: 200}}}
+	_, err = newRetryTransport(stub, 3, 30*time.Second, 0).RoundTrip(req)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Len(t, stub.bodies, 1)
+}
+
+func Test_retryTransport_Timeout(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://api.katapult.io/core/v1/test", nil)
+	require.NoError(t, err)
+
+	// The first attempt times out and is retried. The wait between attempts is longer than the timeout, which
+	// should only apply to each attempt.
+	stub := &stubTransport{responses: []stubResponse{{hang: true}, {status: 503}, {status: 200}}}
+	rt := newRetryTransport(stub, 3, 30*time.Second, 20*time.Millisecond)
+	rt.sleep = func(ctx context.Context, _ time.Duration) error {
+		return sleepContext(ctx, 40*time.Millisecond)
+	}
+	resp, err := rt.RoundTrip(req)
+	require.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Len(t, stub.bodies, 3)
+
+	// The attempt isn't cancelled until the body has been read and closed.
+	assert.NoError(t, resp.Request.Context().Err())
+	require.NoError(t, resp.Body.Close())
+	assert.ErrorIs(t, resp.Request.Context().Err(), context.Canceled)
+
+	// Attempts which time out aren't retried for methods which aren't idempotent.
+	req, err = http.NewRequest(http.MethodPost, "https://api.katapult.io/core/v1/test", nil)
+	require.NoError(t, err)
+	stub = &stubTransport{responses: []stubResponse{{hang: true}, {status: 200}}}
+	_, err = newRetryTransport(stub, 3, 30*time.Second, 20*time.Millisecond).RoundTrip(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Len(t, stub.bodies, 1)
+}
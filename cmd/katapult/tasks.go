@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krystal/go-katapult"
+	"github.com/krystal/go-katapult/core"
+	"github.com/spf13/cobra"
+)
+
+type tasksClient interface {
+	Get(ctx context.Context, id string) (*core.Task, *katapult.Response, error)
+}
+
+// Defines the default time to wait for a task to finish.
+const defaultTaskTimeout = 5 * time.Minute
+
+// Used to wait for a task if --wait is set. A spinner is shown whilst waiting if stdout is a terminal.
+func waitForTaskIfWanted(cmd *cobra.Command, client tasksClient, task *core.Task) (*core.Task, error) {
+	if !waitWanted(cmd) || task == nil {
+		return task, nil
+	}
+
+	ctx, cancel := waitContext(cmd)
+	defer cancel()
+
+	message := func(t *core.Task) string {
+		return fmt.Sprintf("Waiting for task %s to finish (%s, %d%%)...", t.ID, t.Status, t.Progress)
+	}
+	setMessage, stop := progressSpinner(cmd, message(task))
+	defer stop()
+
+	first := true
+	err := pollUntil(ctx, func(ctx context.Context) (bool, error) {
+		if !first {
+			newTask, _, err := client.Get(ctx, task.ID)
+			if err != nil {
+				return false, err
+			}
+			task = newTask
+			setMessage(message(task))
+		}
+		first = false
+
+		switch task.Status {
+		case core.TaskCompleted:
+			return true, nil
+		case core.TaskFailed:
+			return false, fmt.Errorf("task %s failed", task.ID)
+		case core.TaskPending, core.TaskRunning:
+			// Keep polling.
+		}
+		return false, nil
+	})
+	if errors.Is(err, errWaitTimeout) {
+		return task, fmt.Errorf("%w waiting for task %s to finish", errWaitTimeout, task.ID)
+	}
+	return task, err
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/krystal/go-katapult"
+	"github.com/krystal/go-katapult/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTasksClient struct {
+	// Defines the task ID -> the statuses returned by each call to Get. The last status is repeated.
+	statuses map[string][]core.TaskStatus
+
+	// Defines the number of calls to Get.
+	calls int
+}
+
+func (m *mockTasksClient) Get(_ context.Context, id string) (*core.Task, *katapult.Response, error) {
+	statuses, ok := m.statuses[id]
+	if !ok {
+		return nil, nil, core.ErrTaskNotFound
+	}
+	status := statuses[0]
+	if len(statuses) > 1 {
+		m.statuses[id] = statuses[1:]
+	}
+	m.calls++
+	return &core.Task{ID: id, Status: status, Progress: 50}, nil, nil
+}
+
+func TestVMs_Wait(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args     []string
+		statuses map[string][]core.TaskStatus
+		calls    int
+		wantErr  string
+	}{
+		{
+			name:  "no wait",
+			args:  []string{"poweroff", "--id=1"},
+			calls: 0,
+		},
+		{
+			name: "wait for completion",
+			args: []string{"poweroff", "--id=1", "--wait"},
+			statuses: map[string][]core.TaskStatus{
+				"task_1": {core.TaskPending, core.TaskRunning, core.TaskCompleted},
+			},
+			calls: 3,
+		},
+		{
+			name: "wait for failure",
+			args: []string{"stop", "--fqdn=test.example.com", "--wait"},
+			statuses: map[string][]core.TaskStatus{
+				"task_test.example.com": {core.TaskRunning, core.TaskFailed},
+			},
+			calls:   2,
+			wantErr: "task task_test.example.com failed",
+		},
+		{
+			name: "wait timeout",
+			args: []string{"reset", "--id=1", "--wait", "--wait-timeout=50ms"},
+			statuses: map[string][]core.TaskStatus{
+				"task_1": {core.TaskRunning},
+			},
+			wantErr: "timed out waiting for task task_1 to finish",
+		},
+		{
+			name:    "task not found",
+			args:    []string{"poweroff", "--id=1", "--wait"},
+			calls:   0,
+			wantErr: core.ErrTaskNotFound.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pollInterval = time.Millisecond
+			defer func() { pollInterval = 2 * time.Second }()
+			tasks := &mockTasksClient{statuses: tt.statuses}
+			cmd := virtualMachinesCmd(&vmsClient{}, nil, nil, nil, nil, nil, nil, nil, nil, tasks, nil, nil)
+			cmd.SetArgs(tt.args)
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			if tt.wantErr == "" || tt.calls != 0 {
+				assert.Equal(t, tt.calls, tasks.calls)
+			}
+		})
+	}
+}
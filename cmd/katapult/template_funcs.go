@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/augurysys/timestamp"
+	"gopkg.in/yaml.v3"
+)
+
+// Defines the ANSI codes used by the color template function.
+var templateColors = map[string]string{
+	"bold":    "1",
+	"red":     "31",
+	"green":   "32",
+	"yellow":  "33",
+	"blue":    "34",
+	"magenta": "35",
+	"cyan":    "36",
+	"white":   "37",
+	"gray":    "90",
+}
+
+// Used to load the template set with --format. If it starts with @, the rest is the path of a file to load the
+// template from.
+func loadTemplate(tpl string) (string, error) {
+	if !strings.HasPrefix(tpl, "@") {
+		return tpl, nil
+	}
+	b, err := ioutil.ReadFile(tpl[1:])
+	if err != nil {
+		return "", fmt.Errorf("failed to load the template: %w", err)
+	}
+	return string(b), nil
+}
+
+// Used to get the items of a slice or array as interfaces. Nil is returned if the value isn't a slice or array.
+func sliceItems(items interface{}) []reflect.Value {
+	v := reflect.ValueOf(items)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	values := make([]reflect.Value, v.Len())
+	for i := range values {
+		values[i] = v.Index(i)
+	}
+	return values
+}
+
+// Used to get a time from a timestamp, a time or a number of seconds since the Unix epoch. The last is what
+// timestamps are after --query.
+func toTime(v interface{}) (time.Time, bool) {
+	switch x := v.(type) {
+	case *timestamp.Timestamp:
+		if x == nil {
+			return time.Time{}, false
+		}
+		return x.Time(), true
+	case timestamp.Timestamp:
+		return x.Time(), true
+	case time.Time:
+		return x, true
+	case *time.Time:
+		if x == nil {
+			return time.Time{}, false
+		}
+		return *x, true
+	case float64:
+		return time.Unix(int64(x), 0), true
+	case int64:
+		return time.Unix(x, 0), true
+	case int:
+		return time.Unix(int64(x), 0), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// Used to get a number as a float64.
+func toFloat(v interface{}) (float64, bool) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() { //nolint:exhaustive
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(r.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(r.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return r.Float(), true
+	default:
+		return 0, false
+	}
+}
+
+// Used to encode a value as JSON.
+func templateJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// Used to encode a value as YAML.
+func templateYAML(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	return string(b), err
+}
+
+// Used to join the items of a list with a separator.
+func templateJoin(sep string, items interface{}) string {
+	values := sliceItems(items)
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = tableValue(v.Interface())
+	}
+	return strings.Join(s, sep)
+}
+
+// Used to change text to upper case. Values which aren't strings, such as states, are turned into text first.
+func templateUpper(v interface{}) string {
+	return strings.ToUpper(tableValue(v))
+}
+
+// Used to change text to lower case. Values which aren't strings, such as states, are turned into text first.
+func templateLower(v interface{}) string {
+	return strings.ToLower(tableValue(v))
+}
+
+// Used to return a default value if a value is empty, such as a blank string or nil.
+func templateDefault(def, v interface{}) interface{} {
+	if v == nil {
+		return def
+	}
+	r := reflect.ValueOf(v)
+	switch r.Kind() { //nolint:exhaustive
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		if r.Len() == 0 {
+			return def
+		}
+	default:
+		if r.IsZero() {
+			return def
+		}
+	}
+	return v
+}
+
+// Used to get how long ago a time was, such as "3 hours ago".
+func templateTimeAgo(v interface{}) string {
+	t, ok := toTime(v)
+	if !ok {
+		return "unknown"
+	}
+	d := timeNow().Sub(t)
+	if d < 0 {
+		return "in " + humanDuration(-d)
+	}
+	return humanDuration(d) + " ago"
+}
+
+// Used to format a time with a Go time layout, such as "2006-01-02". Times are formatted in UTC.
+func templateDate(layout string, v interface{}) string {
+	t, ok := toTime(v)
+	if !ok {
+		return ""
+	}
+	return t.UTC().Format(layout)
+}
+
+// Used to color text with ANSI codes. Colors are not added when NO_COLOR is set.
+func templateColor(color string, v interface{}) (string, error) {
+	code, ok := templateColors[color]
+	if !ok {
+		return "", fmt.Errorf("unknown color %s", color)
+	}
+	s := tableValue(v)
+	if _, noColor := os.LookupEnv("NO_COLOR"); noColor {
+		return s, nil
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m", nil
+}
+
+// Used to truncate text to a number of characters. If the text is truncated, it ends with "...".
+func templateTruncate(length int, v interface{}) string {
+	s := tableValue(v)
+	if utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	if length <= 3 {
+		return string([]rune(s)[:length])
+	}
+	return string([]rune(s)[:length-3]) + "..."
+}
+
+// Used to format a number of bytes in a human-readable way, such as "1.5 GiB".
+func templateHumanBytes(v interface{}) string {
+	n, ok := toFloat(v)
+	if !ok {
+		return tableValue(v)
+	}
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return strconv.FormatFloat(n, 'f', -1, 64) + " " + units[i]
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64) + " " + units[i]
+}
+
+// Used to get a field from every item in a list. The field uses the same paths as --columns.
+func templatePluck(path string, items interface{}) []interface{} {
+	values := sliceItems(items)
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = valueByPath(v, path)
+	}
+	return result
+}
+
+// Used to get the items in a list where a field equals a value. The field uses the same paths as --columns.
+func templateWhere(path string, value, items interface{}) []interface{} {
+	want := tableValue(value)
+	result := make([]interface{}, 0)
+	for _, v := range sliceItems(items) {
+		if tableValue(valueByPath(v, path)) == want {
+			result = append(result, v.Interface())
+		}
+	}
+	return result
+}
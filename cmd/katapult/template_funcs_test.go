@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/augurysys/timestamp"
+	"github.com/krystal/go-katapult/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+
+		template string
+		item     interface{}
+		noColor  bool
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "json",
+			template: "{{ json . }}",
+			item:     map[string]interface{}{"name": "My Blog", "tags": []string{"a", "b"}},
+			want:     `{"name":"My Blog","tags":["a","b"]}`,
+		},
+		{
+			name:     "yaml",
+			template: "{{ yaml . }}",
+			item:     map[string]interface{}{"name": "My Blog"},
+			want:     "name: My Blog\n",
+		},
+		{
+			name:     "join",
+			template: `{{ join ", " .TagNames }}`,
+			item:     fixtureVirtualMachine,
+			want:     "production, blog",
+		},
+		{
+			name:     "upper and lower",
+			template: "{{ upper .Name }} {{ lower .Name }}",
+			item:     fixtureVirtualMachine,
+			want:     "MY BLOG my blog",
+		},
+		{
+			name:     "upper and lower with a state",
+			template: "{{ upper .State }} {{ lower .State }}",
+			item:     &core.VirtualMachine{State: core.VirtualMachineStarted},
+			want:     "STARTED started",
+		},
+		{
+			name:     "default for blank value",
+			template: `{{ default "none" .Description }}`,
+			item:     struct{ Description string }{},
+			want:     "none",
+		},
+		{
+			name:     "default for nil value",
+			template: `{{ default "none" .Package }}`,
+			item:     struct{ Package *struct{} }{},
+			want:     "none",
+		},
+		{
+			name:     "default for set value",
+			template: `{{ default "none" .Description }}`,
+			item:     fixtureVirtualMachine,
+			want:     "test",
+		},
+		{
+			name:     "time ago",
+			template: "{{ timeAgo . }}",
+			item:     timestamp.Unix(mockNow.Add(-26*time.Hour).Unix(), 0),
+			want:     "1 day 2 hours ago",
+		},
+		{
+			name:     "time ago in the future",
+			template: "{{ timeAgo . }}",
+			item:     timestamp.Unix(mockNow.Add(3*time.Hour).Unix(), 0),
+			want:     "in 3 hours",
+		},
+		{
+			name:     "time ago from a query",
+			template: "{{ timeAgo . }}",
+			item:     float64(mockNow.Add(-2 * time.Minute).Unix()),
+			want:     "2 minutes ago",
+		},
+		{
+			name:     "date",
+			template: `{{ date "2006-01-02 15:04" .CreatedAt }}`,
+			item:     fixtureVirtualMachine,
+			want:     "2021-08-01 12:00",
+		},
+		{
+			name:     "color",
+			template: `{{ color "green" .State }}`,
+			item:     fixtureVirtualMachine,
+			want:     "\x1b[32mstarted\x1b[0m",
+		},
+		{
+			name:     "color with NO_COLOR",
+			template: `{{ color "green" .State }}`,
+			item:     fixtureVirtualMachine,
+			noColor:  true,
+			want:     "started",
+		},
+		{
+			name:     "unknown color",
+			template: `{{ color "pink" .State }}`,
+			item:     fixtureVirtualMachine,
+			wantErr:  "unknown color pink",
+		},
+		{
+			name:     "truncate",
+			template: "{{ truncate 10 .FQDN }}|{{ truncate 10 .Name }}",
+			item:     fixtureVirtualMachine,
+			want:     "my-blog...|My Blog",
+		},
+		{
+			name:     "human bytes",
+			template: "{{ humanBytes 512 }} {{ humanBytes 1536 }} {{ humanBytes 10737418240 }}",
+			want:     "512 B 1.5 KiB 10.0 GiB",
+		},
+		{
+			name:     "pluck",
+			template: `{{ join "," (pluck "country.name" .) }}`,
+			item:     fixtureDataCenters,
+			want:     "Pogland,United Kingdom",
+		},
+		{
+			name:     "pluck nested fields",
+			template: `{{ join "," (pluck "address" .IPAddresses) }}`,
+			item:     fixtureVirtualMachine,
+			want:     "1.1.1.1,2a03:2800::1",
+		},
+		{
+			name:     "where",
+			template: `{{ range where "state" "started" . }}{{ .Name }} {{ end }}`,
+			item:     []interface{}{fixtureVirtualMachine, map[string]string{"Name": "Stopped", "state": "stopped"}},
+			want:     "My Blog ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeNow = func() time.Time { return mockNow }
+			defer func() { timeNow = time.Now }()
+			if tt.noColor {
+				require.NoError(t, os.Setenv("NO_COLOR", "1"))
+				defer os.Unsetenv("NO_COLOR")
+			}
+
+			buf := &bytes.Buffer{}
+			err := renderTemplate(buf, tt.template, tt.item)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestTemplateFile(t *testing.T) {
+	tests := []struct {
+		name string
+
+		template string
+		wantErr  string
+	}{
+		{
+			name:     "template file",
+			template: "{{ range . }}{{ .Name }}: {{ timeAgo .CreatedAt }}\n{{ end }}",
+		},
+		{
+			name:    "missing file",
+			wantErr: "failed to load the template: open ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "list.tmpl")
+			if tt.template != "" {
+				require.NoError(t, ioutil.WriteFile(path, []byte(tt.template), 0o600))
+			}
+
+			timeNow = func() time.Time { return mockNow.Add(time.Hour) }
+			defer func() { timeNow = time.Now }()
+			pages := map[string]vmPages{"loge": {{fixtureVirtualMachine}}}
+			cmd := virtualMachinesCmd(
+				&vmsClient{organizationSubdomainPages: pages}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd.SetArgs([]string{"list", "loge"})
+			templateFlag = "@" + path
+			defer func() { templateFlag = "" }()
+			if tt.wantErr == "" {
+				assertCobraCommand(t, cmd, "", "")
+				return
+			}
+			cmd.SetOut(ioutil.Discard)
+			cmd.SetErr(ioutil.Discard)
+			err := cmd.Execute()
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
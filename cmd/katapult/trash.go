@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/krystal/go-katapult"
+	"github.com/krystal/go-katapult/core"
+	"github.com/krystal/katapult-cli/cmd/katapult/console"
+	"github.com/spf13/cobra"
+)
+
+type trashObjectsClient interface {
+	List(
+		ctx context.Context,
+		org core.OrganizationRef,
+		opts *core.ListOptions,
+	) ([]*core.TrashObject, *katapult.Response, error)
+	Restore(
+		ctx context.Context,
+		ref core.TrashObjectRef,
+	) (*core.TrashObject, *katapult.Response, error)
+	Purge(
+		ctx context.Context,
+		ref core.TrashObjectRef,
+	) (*core.Task, *katapult.Response, error)
+	PurgeAll(
+		ctx context.Context,
+		org core.OrganizationRef,
+	) (*core.Task, *katapult.Response, error)
+}
+
+func trashNotFoundHandlingError(err error) error {
+	if errors.Is(err, core.ErrTrashObjectNotFound) {
+		return fmt.Errorf("unknown trash object")
+	}
+	return err
+}
+
+// Defines the columns of trash list.
+var trashListColumns = []*outputColumn{
+	{Header: "ID", Path: "ID"},
+	{Header: "Object Type", Path: "ObjectType"},
+	{Header: "Object ID", Path: "ObjectID"},
+	{Header: "Keep Until", Path: "KeepUntil"},
+}
+
+func trashListCmd(client trashObjectsClient) *cobra.Command {
+	list := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Get a list of trash objects from an organization",
+		Long:    "Get a list of trash objects from an organization.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			ref, err := getOrgRef(cmd)
+			if err != nil {
+				return nil, err
+			}
+
+			return &pagedOutput{
+				ctx: cmd.Context(),
+				fetch: func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+					return client.List(ctx, ref, opts)
+				},
+				columns: trashListColumns,
+			}, nil
+		}),
+	}
+	listFlags := list.Flags()
+	listFlags.String("id", "", "The ID of the organization. Preferred over subdomain for lookups.")
+	listFlags.String("subdomain", "", "The subdomain of the organization.")
+	return list
+}
+
+func trashRestoreCmd(client trashObjectsClient) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore",
+		Args:  cobra.ExactArgs(1),
+		Short: "Restore an object from the trash",
+		Long:  "Restore an object from the trash. The argument should be the ID of the trash object.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			trashObject, _, err := client.Restore(cmd.Context(), core.TrashObjectRef{ID: args[0]})
+			if err != nil {
+				return nil, trashNotFoundHandlingError(err)
+			}
+			return &genericOutput{
+				item:                trashObject,
+				defaultTextTemplate: "Trash object successfully restored.\n",
+			}, nil
+		}),
+	}
+}
+
+func trashPurgeCmd(client trashObjectsClient) *cobra.Command {
+	purge := &cobra.Command{
+		Use:   "purge",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Permanently delete objects in the trash",
+		Long: "Permanently delete objects in the trash. The argument should be the ID of the trash object. " +
+			"If --all is set, every trash object in the organization is purged instead.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			all, _ := cmd.Flags().GetBool("all")
+			yes, _ := cmd.Flags().GetBool("yes")
+
+			if all {
+				if len(args) != 0 {
+					return nil, errors.New("a trash object ID cannot be specified with --all")
+				}
+				ref, err := getOrgRef(cmd)
+				if err != nil {
+					return nil, err
+				}
+				if !yes && !console.Confirm(
+					"Are you sure you want to permanently delete everything in the trash?",
+					cmd.InOrStdin(), cmd.ErrOrStderr()) {
+					return nil, errors.New("purge cancelled")
+				}
+				task, _, err := client.PurgeAll(cmd.Context(), ref)
+				if err != nil {
+					return nil, err
+				}
+				return &genericOutput{
+					item:                task,
+					defaultTextTemplate: "Purge of all trash objects successfully queued.\n",
+				}, nil
+			}
+
+			if len(args) == 0 {
+				return nil, errors.New("the trash object ID is unset")
+			}
+			question := fmt.Sprintf("Are you sure you want to permanently delete the trash object %s?", args[0])
+			if !yes && !console.Confirm(question, cmd.InOrStdin(), cmd.ErrOrStderr()) {
+				return nil, errors.New("purge cancelled")
+			}
+			task, _, err := client.Purge(cmd.Context(), core.TrashObjectRef{ID: args[0]})
+			if err != nil {
+				return nil, trashNotFoundHandlingError(err)
+			}
+			return &genericOutput{
+				item:                task,
+				defaultTextTemplate: "Purge of trash object successfully queued.\n",
+			}, nil
+		}),
+	}
+	purgeFlags := purge.Flags()
+	purgeFlags.Bool("all", false, "Purge every trash object in the organization.")
+	purgeFlags.String("id", "", "The ID of the organization when using --all. Preferred over subdomain for lookups.")
+	purgeFlags.String("subdomain", "", "The subdomain of the organization when using --all.")
+	purgeFlags.BoolP("yes", "y", false, "Skip the confirmation prompt.")
+	return purge
+}
+
+func trashCmd(client trashObjectsClient) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "trash",
+		Short: "Manage the trash of an organization",
+		Long:  "Get information about and restore or purge objects in the trash of an organization.",
+	}
+
+	cmd.AddCommand(
+		trashListCmd(client),
+		trashRestoreCmd(client),
+		trashPurgeCmd(client),
+	)
+
+	return cmd
+}
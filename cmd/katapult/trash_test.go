@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/augurysys/timestamp"
+	"github.com/krystal/go-katapult"
+	"github.com/krystal/go-katapult/core"
+	"github.com/stretchr/testify/assert"
+)
+
+type trashPages [][]*core.TrashObject
+
+type mockTrashObjectsClient struct {
+	// Defines the organization ID -> trashPages.
+	organizationIDPages map[string]trashPages
+
+	// Defines the organization subdomain -> trashPages.
+	organizationSubdomainPages map[string]trashPages
+
+	// Defines the trash objects which were restored.
+	restored []string
+
+	// Defines the trash objects which were purged.
+	purged []string
+
+	// Defines the organizations which had all of their trash purged.
+	purgedAll []core.OrganizationRef
+}
+
+func (m *mockTrashObjectsClient) getPages(org core.OrganizationRef) (trashPages, error) {
+	switch {
+	case org.ID != "":
+		if pages, ok := m.organizationIDPages[org.ID]; ok {
+			return pages, nil
+		}
+	case org.SubDomain != "":
+		if pages, ok := m.organizationSubdomainPages[org.SubDomain]; ok {
+			return pages, nil
+		}
+	}
+	return nil, core.ErrOrganizationNotFound
+}
+
+func (m *mockTrashObjectsClient) findTrashObject(id string) *core.TrashObject {
+	pageSets := []map[string]trashPages{m.organizationIDPages, m.organizationSubdomainPages}
+	for _, pageSet := range pageSets {
+		for _, pages := range pageSet {
+			for _, page := range pages {
+				for _, trashObject := range page {
+					if trashObject.ID == id {
+						return trashObject
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mockTrashObjectsClient) List(_ context.Context, org core.OrganizationRef, opts *core.ListOptions) (
+	[]*core.TrashObject, *katapult.Response, error,
+) {
+	pages, err := m.getPages(org)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.Page > len(pages) {
+		return nil, nil, katapult.ErrNotFound
+	}
+	page := pages[opts.Page-1]
+	return page, &katapult.Response{Pagination: &katapult.Pagination{
+		CurrentPage: opts.Page,
+		TotalPages:  len(pages),
+		PerPage:     len(page),
+	}}, nil
+}
+
+func (m *mockTrashObjectsClient) Restore(_ context.Context, ref core.TrashObjectRef) (
+	*core.TrashObject, *katapult.Response, error,
+) {
+	trashObject := m.findTrashObject(ref.ID)
+	if trashObject == nil {
+		return nil, nil, core.ErrTrashObjectNotFound
+	}
+	m.restored = append(m.restored, ref.ID)
+	return trashObject, nil, nil
+}
+
+func (m *mockTrashObjectsClient) Purge(_ context.Context, ref core.TrashObjectRef) (
+	*core.Task, *katapult.Response, error,
+) {
+	if m.findTrashObject(ref.ID) == nil {
+		return nil, nil, core.ErrTrashObjectNotFound
+	}
+	m.purged = append(m.purged, ref.ID)
+	return &core.Task{ID: "task_purge", Name: "Purge trash object", Status: core.TaskPending}, nil, nil
+}
+
+func (m *mockTrashObjectsClient) PurgeAll(_ context.Context, org core.OrganizationRef) (
+	*core.Task, *katapult.Response, error,
+) {
+	if _, err := m.getPages(org); err != nil {
+		return nil, nil, err
+	}
+	m.purgedAll = append(m.purgedAll, org)
+	return &core.Task{ID: "task_purge_all", Name: "Purge all trash objects", Status: core.TaskPending}, nil, nil
+}
+
+func newMockTrashObjectsClient() *mockTrashObjectsClient {
+	keepUntil := timestamp.Unix(mockNow.Add(7*24*time.Hour).Unix(), 0)
+	pages := trashPages{
+		{
+			{ID: "trsh_1", KeepUntil: keepUntil, ObjectID: "vm_1", ObjectType: "VirtualMachine"},
+			{ID: "trsh_2", KeepUntil: keepUntil, ObjectID: "vm_2", ObjectType: "VirtualMachine"},
+		},
+		{
+			{ID: "trsh_3", KeepUntil: keepUntil, ObjectID: "disk_1", ObjectType: "Disk"},
+		},
+	}
+	return &mockTrashObjectsClient{
+		organizationIDPages:        map[string]trashPages{"org_1": pages},
+		organizationSubdomainPages: map[string]trashPages{"loge": pages},
+	}
+}
+
+func TestTrash_List(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		output  string
+		wantErr string
+	}{
+		{
+			name:    "no ID/subdomain provided",
+			args:    []string{"list"},
+			wantErr: "both ID and subdomain are unset",
+		},
+		{
+			name: "paginated list by ID",
+			args: []string{"list", "--id", "org_1"},
+		},
+		{
+			name:   "paginated list by subdomain json",
+			args:   []string{"list", "--subdomain", "loge"},
+			output: "json",
+		},
+		{
+			name:   "paginated list by subdomain csv",
+			args:   []string{"list", "--subdomain", "loge"},
+			output: "csv",
+		},
+		{
+			name:   "paginated list by subdomain ndjson",
+			args:   []string{"list", "--subdomain", "loge"},
+			output: "ndjson",
+		},
+		{
+			name:    "unknown organization",
+			args:    []string{"list", "--subdomain", "unknown"},
+			wantErr: core.ErrOrganizationNotFound.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := trashCmd(newMockTrashObjectsClient())
+			cmd.SetArgs(tt.args)
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			outputFlag = ""
+		})
+	}
+}
+
+func TestTrash_Restore(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args     []string
+		restored []string
+		wantErr  string
+	}{
+		{
+			name:    "no ID provided",
+			args:    []string{"restore"},
+			wantErr: "accepts 1 arg(s), received 0",
+		},
+		{
+			name:     "restore trash object",
+			args:     []string{"restore", "trsh_2"},
+			restored: []string{"trsh_2"},
+		},
+		{
+			name:    "unknown trash object",
+			args:    []string{"restore", "trsh_unknown"},
+			wantErr: "unknown trash object",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockTrashObjectsClient()
+			cmd := trashCmd(client)
+			cmd.SetArgs(tt.args)
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			assert.Equal(t, tt.restored, client.restored)
+		})
+	}
+}
+
+func TestTrash_Purge(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args      []string
+		stdin     string
+		purged    []string
+		purgedAll []core.OrganizationRef
+		stderr    string
+		wantErr   string
+	}{
+		{
+			name:    "no ID provided",
+			args:    []string{"purge", "--yes"},
+			wantErr: "the trash object ID is unset",
+		},
+		{
+			name:   "purge trash object",
+			args:   []string{"purge", "trsh_1", "--yes"},
+			purged: []string{"trsh_1"},
+		},
+		{
+			name:   "purge trash object confirmed",
+			args:   []string{"purge", "trsh_1"},
+			stdin:  "yes\n",
+			stderr: "\x1b[36mAre you sure you want to permanently delete the trash object trsh_1? [y/N]\x1b[0m ",
+			purged: []string{"trsh_1"},
+		},
+		{
+			name:    "purge trash object declined",
+			args:    []string{"purge", "trsh_1"},
+			wantErr: "purge cancelled",
+		},
+		{
+			name:    "unknown trash object",
+			args:    []string{"purge", "trsh_unknown", "--yes"},
+			wantErr: "unknown trash object",
+		},
+		{
+			name:      "purge all",
+			args:      []string{"purge", "--all", "--subdomain", "loge", "--yes"},
+			purgedAll: []core.OrganizationRef{{SubDomain: "loge"}},
+		},
+		{
+			name:    "purge all without organization",
+			args:    []string{"purge", "--all", "--yes"},
+			wantErr: "both ID and subdomain are unset",
+		},
+		{
+			name:    "purge all with ID",
+			args:    []string{"purge", "trsh_1", "--all", "--id", "org_1", "--yes"},
+			wantErr: "a trash object ID cannot be specified with --all",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newMockTrashObjectsClient()
+			cmd := trashCmd(client)
+			cmd.SetArgs(tt.args)
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
+			assert.Equal(t, tt.purged, client.purged)
+			assert.Equal(t, tt.purgedAll, client.purgedAll)
+		})
+	}
+}
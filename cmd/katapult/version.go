@@ -73,6 +73,7 @@ func versionCommand() *cobra.Command {
 				defaultTextTemplate: versionFormat,
 			}, nil
 		}),
+		Annotations: map[string]string{noTokenAnnotation: "true"},
 	}
 
 	return versionCmd
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/krystal/go-katapult"
 	"github.com/krystal/go-katapult/buildspec"
@@ -21,6 +22,10 @@ type virtualMachinesClient interface {
 		org core.OrganizationRef,
 		opts *core.ListOptions,
 	) ([]*core.VirtualMachine, *katapult.Response, error)
+	Get(
+		ctx context.Context,
+		ref core.VirtualMachineRef,
+	) (*core.VirtualMachine, *katapult.Response, error)
 	Delete(
 		ctx context.Context,
 		ref core.VirtualMachineRef,
@@ -63,7 +68,22 @@ func vmNotFoundHandlingError(err error) error {
 	return err
 }
 
-const virtualMachineListFormat = `{{ Table (StringSlice "Name" "FQDN") (MultipleRows . "Name" "FQDN") }}`
+// Defines the columns of vm list.
+var virtualMachineListColumns = []*outputColumn{
+	{Header: "Name", Path: "Name"},
+	{Header: "FQDN", Path: "FQDN"},
+}
+
+// Defines the columns of vm list -o wide.
+var virtualMachineListWideColumns = []*outputColumn{
+	{Header: "ID", Path: "ID"},
+	{Header: "Name", Path: "Name"},
+	{Header: "FQDN", Path: "FQDN"},
+	{Header: "State", Path: "State"},
+	{Header: "Package", Path: "Package.Name"},
+	{Header: "Zone", Path: "Zone.Name"},
+	{Header: "Created At", Path: "CreatedAt"},
+}
 
 func virtualMachinesListCmd(client virtualMachinesClient) *cobra.Command {
 	list := &cobra.Command{
@@ -77,29 +97,22 @@ func virtualMachinesListCmd(client virtualMachinesClient) *cobra.Command {
 			ref := core.OrganizationRef{ID: cmd.Flags().Lookup("org-id").Value.String()}
 			if ref.ID == "" {
 				if len(args) == 0 || args[0] == "" {
-					return nil, fmt.Errorf("both ID and subdomain are unset")
-				}
-				ref.SubDomain = args[0]
-			}
-
-			totalPages := 1
-			allVms := make([]*core.VirtualMachine, 0)
-			for pageNum := 1; pageNum <= totalPages; pageNum++ {
-				vms, resp, err := client.List(
-					cmd.Context(), ref, &core.ListOptions{Page: pageNum},
-				)
-				if err != nil {
-					return nil, err
-				}
-				if resp.Pagination != nil {
-					totalPages = resp.Pagination.TotalPages
+					var ok bool
+					if ref, ok = defaultOrgRef(); !ok {
+						return nil, fmt.Errorf("both ID and subdomain are unset")
+					}
+				} else {
+					ref.SubDomain = args[0]
 				}
-				allVms = append(allVms, vms...)
 			}
 
-			return &genericOutput{
-				item:                allVms,
-				defaultTextTemplate: virtualMachineListFormat,
+			return &pagedOutput{
+				ctx: cmd.Context(),
+				fetch: func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+					return client.List(ctx, ref, opts)
+				},
+				columns:     virtualMachineListColumns,
+				wideColumns: virtualMachineListWideColumns,
 			}, nil
 		}),
 	}
@@ -107,7 +120,48 @@ func virtualMachinesListCmd(client virtualMachinesClient) *cobra.Command {
 	return list
 }
 
-func virtualMachinesPoweroffCmd(client virtualMachinesClient) *cobra.Command {
+const virtualMachineGetFormat = `ID: {{ .ID }}
+Name: {{ .Name }}
+Hostname: {{ .Hostname }}
+FQDN: {{ .FQDN }}
+Description: {{ .Description }}
+State: {{ .State }}
+Package: {{ with .Package }}{{ .Name }}{{ end }}
+Data Center: {{ with .Zone }}{{ with .DataCenter }}{{ .Name }}{{ end }}{{ end }}
+Zone: {{ with .Zone }}{{ .Name }}{{ end }}
+Created At: {{ with .CreatedAt }}{{ .Time.UTC.Format "2006-01-02T15:04:05Z07:00" }}{{ end }}
+IP Addresses:{{ range .IPAddresses }}
+  - {{ .Address }}{{ end }}
+Tags:{{ range .TagNames }}
+  - {{ . }}{{ end }}
+`
+
+func virtualMachinesGetCmd(client virtualMachinesClient) *cobra.Command {
+	get := &cobra.Command{
+		Use:   "get",
+		Short: "Get details for a virtual machine",
+		Long:  "Get details for a virtual machine.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			ref, err := getVMRef(cmd)
+			if err != nil {
+				return nil, err
+			}
+			vm, _, err := client.Get(cmd.Context(), ref)
+			if err != nil {
+				return nil, vmNotFoundHandlingError(err)
+			}
+			return &genericOutput{
+				item:                vm,
+				defaultTextTemplate: virtualMachineGetFormat,
+			}, nil
+		}),
+	}
+	get.Flags().String("id", "", "The ID of the server. If set, this takes priority over the FQDN.")
+	get.Flags().String("fqdn", "", "The FQDN of the server.")
+	return get
+}
+
+func virtualMachinesPoweroffCmd(client virtualMachinesClient, tasks tasksClient) *cobra.Command {
 	poweroff := &cobra.Command{
 		Use:   "poweroff",
 		Short: "Used to power off a virtual machine.",
@@ -121,6 +175,10 @@ func virtualMachinesPoweroffCmd(client virtualMachinesClient) *cobra.Command {
 			if err != nil {
 				return nil, vmNotFoundHandlingError(err)
 			}
+			task, err = waitForTaskIfWanted(cmd, tasks, task)
+			if err != nil {
+				return nil, err
+			}
 			return &genericOutput{
 				item:                task,
 				defaultTextTemplate: "Virtual machine successfully powered down.\n",
@@ -129,10 +187,11 @@ func virtualMachinesPoweroffCmd(client virtualMachinesClient) *cobra.Command {
 	}
 	poweroff.Flags().String("id", "", "The ID of the server. If set, this takes priority over the FQDN.")
 	poweroff.Flags().String("fqdn", "", "The FQDN of the server.")
+	addWaitFlags(poweroff, defaultTaskTimeout)
 	return poweroff
 }
 
-func virtualMachinesStartCmd(client virtualMachinesClient) *cobra.Command {
+func virtualMachinesStartCmd(client virtualMachinesClient, tasks tasksClient) *cobra.Command {
 	start := &cobra.Command{
 		Use:   "start",
 		Short: "Used to start a virtual machine.",
@@ -146,6 +205,10 @@ func virtualMachinesStartCmd(client virtualMachinesClient) *cobra.Command {
 			if err != nil {
 				return nil, vmNotFoundHandlingError(err)
 			}
+			task, err = waitForTaskIfWanted(cmd, tasks, task)
+			if err != nil {
+				return nil, err
+			}
 			return &genericOutput{
 				item:                task,
 				defaultTextTemplate: "Virtual machine successfully started.\n",
@@ -154,10 +217,11 @@ func virtualMachinesStartCmd(client virtualMachinesClient) *cobra.Command {
 	}
 	start.Flags().String("id", "", "The ID of the server. If set, this takes priority over the FQDN.")
 	start.Flags().String("fqdn", "", "The FQDN of the server.")
+	addWaitFlags(start, defaultTaskTimeout)
 	return start
 }
 
-func virtualMachinesStopCmd(client virtualMachinesClient) *cobra.Command {
+func virtualMachinesStopCmd(client virtualMachinesClient, tasks tasksClient) *cobra.Command {
 	stop := &cobra.Command{
 		Use:   "stop",
 		Short: "Used to stop a virtual machine.",
@@ -171,6 +235,10 @@ func virtualMachinesStopCmd(client virtualMachinesClient) *cobra.Command {
 			if err != nil {
 				return nil, vmNotFoundHandlingError(err)
 			}
+			task, err = waitForTaskIfWanted(cmd, tasks, task)
+			if err != nil {
+				return nil, err
+			}
 			return &genericOutput{
 				item:                task,
 				defaultTextTemplate: "Virtual machine successfully stopped.\n",
@@ -179,10 +247,11 @@ func virtualMachinesStopCmd(client virtualMachinesClient) *cobra.Command {
 	}
 	stop.Flags().String("id", "", "The ID of the server. If set, this takes priority over the FQDN.")
 	stop.Flags().String("fqdn", "", "The FQDN of the server.")
+	addWaitFlags(stop, defaultTaskTimeout)
 	return stop
 }
 
-func virtualMachinesResetCmd(client virtualMachinesClient) *cobra.Command {
+func virtualMachinesResetCmd(client virtualMachinesClient, tasks tasksClient) *cobra.Command {
 	reset := &cobra.Command{
 		Use:   "reset",
 		Short: "Used to reset a virtual machine.",
@@ -196,6 +265,10 @@ func virtualMachinesResetCmd(client virtualMachinesClient) *cobra.Command {
 			if err != nil {
 				return nil, vmNotFoundHandlingError(err)
 			}
+			task, err = waitForTaskIfWanted(cmd, tasks, task)
+			if err != nil {
+				return nil, err
+			}
 			return &genericOutput{
 				item:                task,
 				defaultTextTemplate: "Virtual machine successfully reset.\n",
@@ -204,9 +277,56 @@ func virtualMachinesResetCmd(client virtualMachinesClient) *cobra.Command {
 	}
 	reset.Flags().String("id", "", "The ID of the server. If set, this takes priority over the FQDN.")
 	reset.Flags().String("fqdn", "", "The FQDN of the server.")
+	addWaitFlags(reset, defaultTaskTimeout)
 	return reset
 }
 
+const virtualMachineDeleteFormat = `Virtual machine successfully moved to the trash.
+Trash object ID: {{ .ID }}
+Purged in: {{ TimeUntil .KeepUntil }}
+`
+
+func virtualMachinesDeleteCmd(client virtualMachinesClient) *cobra.Command {
+	deleteCmd := &cobra.Command{
+		Use:     "delete",
+		Aliases: []string{"rm"},
+		Short:   "Used to delete a virtual machine.",
+		Long: "Used to delete a virtual machine. The virtual machine is moved to the trash of the organization " +
+			"and can be restored until it is purged.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			ref, err := getVMRef(cmd)
+			if err != nil {
+				return nil, err
+			}
+
+			// Ask the user to confirm the deletion unless --yes was passed.
+			if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+				identifier := ref.ID
+				if identifier == "" {
+					identifier = ref.FQDN
+				}
+				question := fmt.Sprintf("Are you sure you want to delete the virtual machine %s?", identifier)
+				if !console.Confirm(question, cmd.InOrStdin(), cmd.ErrOrStderr()) {
+					return nil, errors.New("deletion cancelled")
+				}
+			}
+
+			trashObject, _, err := client.Delete(cmd.Context(), ref)
+			if err != nil {
+				return nil, vmNotFoundHandlingError(err)
+			}
+			return &genericOutput{
+				item:                trashObject,
+				defaultTextTemplate: virtualMachineDeleteFormat,
+			}, nil
+		}),
+	}
+	deleteCmd.Flags().String("id", "", "The ID of the server. If set, this takes priority over the FQDN.")
+	deleteCmd.Flags().String("fqdn", "", "The FQDN of the server.")
+	deleteCmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt.")
+	return deleteCmd
+}
+
 type virtualMachinePackagesClient interface {
 	List(
 		ctx context.Context,
@@ -216,36 +336,22 @@ type virtualMachinePackagesClient interface {
 
 func listAllVMPackages(ctx context.Context,
 	vmPackagesClient virtualMachinePackagesClient) ([]*core.VirtualMachinePackage, error) {
-	totalPages := 1
 	allPackages := make([]*core.VirtualMachinePackage, 0)
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		packages, resp, err := vmPackagesClient.List(ctx, &core.ListOptions{Page: pageNum})
-		if err != nil {
-			return nil, err
-		}
-		if resp.Pagination != nil {
-			totalPages = resp.Pagination.TotalPages
-		}
-		allPackages = append(allPackages, packages...)
+	fetch := func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+		return vmPackagesClient.List(ctx, opts)
 	}
-	return allPackages, nil
+	err := listAllPages(ctx, fetch, &allPackages)
+	return allPackages, err
 }
 
 func listAllIPAddresses(ctx context.Context, org core.OrganizationRef,
 	ipAddressesClient virtualMachineIPAddressesClient) ([]*core.IPAddress, error) {
-	totalPages := 1
 	allAddresses := make([]*core.IPAddress, 0)
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		addresses, resp, err := ipAddressesClient.List(ctx, org, &core.ListOptions{Page: pageNum})
-		if err != nil {
-			return nil, err
-		}
-		if resp.Pagination != nil {
-			totalPages = resp.Pagination.TotalPages
-		}
-		allAddresses = append(allAddresses, addresses...)
+	fetch := func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+		return ipAddressesClient.List(ctx, org, opts)
 	}
-	return allAddresses, nil
+	err := listAllPages(ctx, fetch, &allAddresses)
+	return allAddresses, err
 }
 
 type virtualMachineDiskTemplatesClient interface {
@@ -258,53 +364,33 @@ type virtualMachineDiskTemplatesClient interface {
 
 func listAllDiskTemplates(ctx context.Context, org core.OrganizationRef,
 	diskTemplatesClient virtualMachineDiskTemplatesClient) ([]*core.DiskTemplate, error) {
-	totalPages := 1
 	allImages := make([]*core.DiskTemplate, 0)
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		images, resp, err := diskTemplatesClient.List(
-			ctx, org, &core.DiskTemplateListOptions{Page: pageNum, IncludeUniversal: true})
-		if err != nil {
-			return nil, err
-		}
-		if resp.Pagination != nil {
-			totalPages = resp.Pagination.TotalPages
-		}
-		allImages = append(allImages, images...)
+	fetch := func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+		return diskTemplatesClient.List(ctx, org, &core.DiskTemplateListOptions{
+			Page: opts.Page, PerPage: opts.PerPage, IncludeUniversal: true,
+		})
 	}
-	return allImages, nil
+	err := listAllPages(ctx, fetch, &allImages)
+	return allImages, err
 }
 
 func listAllTags(ctx context.Context, org core.OrganizationRef, tagsClient tagsClient) ([]*core.Tag, error) {
-	totalPages := 1
 	allTags := make([]*core.Tag, 0)
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		tags, resp, err := tagsClient.List(ctx, org, &core.ListOptions{Page: pageNum})
-		if err != nil {
-			return nil, err
-		}
-		if resp.Pagination != nil {
-			totalPages = resp.Pagination.TotalPages
-		}
-		allTags = append(allTags, tags...)
+	fetch := func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+		return tagsClient.List(ctx, org, opts)
 	}
-	return allTags, nil
+	err := listAllPages(ctx, fetch, &allTags)
+	return allTags, err
 }
 
 func listAllSSHKeys(ctx context.Context, org core.OrganizationRef,
 	sshKeysClient sshKeysListClient) ([]*core.AuthSSHKey, error) {
-	totalPages := 1
 	allKeys := make([]*core.AuthSSHKey, 0)
-	for pageNum := 1; pageNum <= totalPages; pageNum++ {
-		keys, resp, err := sshKeysClient.List(ctx, org, &core.ListOptions{Page: pageNum})
-		if err != nil {
-			return nil, err
-		}
-		if resp.Pagination != nil {
-			totalPages = resp.Pagination.TotalPages
-		}
-		allKeys = append(allKeys, keys...)
+	fetch := func(ctx context.Context, opts *core.ListOptions) (interface{}, *katapult.Response, error) {
+		return sshKeysClient.List(ctx, org, opts)
 	}
-	return allKeys, nil
+	err := listAllPages(ctx, fetch, &allKeys)
+	return allKeys, err
 }
 
 func getStringIndex(needle string, haystack []string) int {
@@ -355,6 +441,120 @@ type virtualMachinesBuilderClient interface {
 		org core.OrganizationRef,
 		spec *buildspec.VirtualMachineSpec,
 	) (*core.VirtualMachineBuild, *katapult.Response, error)
+	Get(
+		ctx context.Context,
+		ref core.VirtualMachineBuildRef,
+	) (*core.VirtualMachineBuild, *katapult.Response, error)
+}
+
+// Defines the default time to wait for a virtual machine build to finish.
+const defaultVMBuildTimeout = 15 * time.Minute
+
+const virtualMachineBuildFormat = `Build ID: {{ .ID }}
+State: {{ .State }}
+{{- with .VirtualMachine }}
+Virtual Machine ID: {{ .ID }}
+FQDN: {{ .FQDN }}
+{{- end }}
+`
+
+const virtualMachineBuiltFormat = `Virtual machine successfully built.
+ID: {{ .ID }}
+FQDN: {{ .FQDN }}
+IP Addresses:{{ range .IPAddresses }}
+  - {{ .Address }}{{ end }}
+`
+
+// Used to wait for a virtual machine build to finish. A spinner is shown whilst waiting if stdout is a terminal.
+func waitForVMBuild(
+	cmd *cobra.Command, client virtualMachinesBuilderClient, build *core.VirtualMachineBuild,
+) (*core.VirtualMachineBuild, error) {
+	ctx, cancel := waitContext(cmd)
+	defer cancel()
+
+	message := func(b *core.VirtualMachineBuild) string {
+		return fmt.Sprintf("Waiting for virtual machine build %s to finish (%s)...", b.ID, b.State)
+	}
+	setMessage, stop := progressSpinner(cmd, message(build))
+	defer stop()
+
+	err := pollUntil(ctx, func(ctx context.Context) (bool, error) {
+		newBuild, _, err := client.Get(ctx, build.Ref())
+		if err != nil {
+			return false, err
+		}
+		build = newBuild
+		setMessage(message(build))
+
+		switch build.State {
+		case core.VirtualMachineBuildComplete:
+			return true, nil
+		case core.VirtualMachineBuildFailed:
+			return false, fmt.Errorf("virtual machine build %s failed", build.ID)
+		case core.VirtualMachineBuildDraft, core.VirtualMachineBuildPending, core.VirtualMachineBuildBuilding:
+			// Keep polling.
+		}
+		return false, nil
+	})
+	if errors.Is(err, errWaitTimeout) {
+		return build, fmt.Errorf("%w waiting for virtual machine build %s to finish", errWaitTimeout, build.ID)
+	}
+	return build, err
+}
+
+// Used to get the output for a virtual machine build. If --wait is set, this waits for the build to finish and
+// outputs the resulting virtual machine.
+func vmBuildOutput(
+	cmd *cobra.Command, vmClient virtualMachinesClient, vmBuilderClient virtualMachinesBuilderClient,
+	build *core.VirtualMachineBuild,
+) (Output, error) {
+	if !waitWanted(cmd) {
+		return &genericOutput{
+			item:                build,
+			defaultTextTemplate: virtualMachineBuildFormat,
+		}, nil
+	}
+
+	build, err := waitForVMBuild(cmd, vmBuilderClient, build)
+	if err != nil {
+		return nil, err
+	}
+	if build.VirtualMachine == nil {
+		return nil, fmt.Errorf("virtual machine build %s has no virtual machine", build.ID)
+	}
+
+	// The build only contains a partial virtual machine, so fetch the full one.
+	vm, _, err := vmClient.Get(cmd.Context(), build.VirtualMachine.Ref())
+	if err != nil {
+		return nil, vmNotFoundHandlingError(err)
+	}
+	return &genericOutput{
+		item:                vm,
+		defaultTextTemplate: virtualMachineBuiltFormat,
+	}, nil
+}
+
+func virtualMachinesBuildStatusCmd(
+	vmClient virtualMachinesClient, vmBuilderClient virtualMachinesBuilderClient,
+) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "build-status",
+		Args:  cobra.ExactArgs(1),
+		Short: "Get the status of a virtual machine build",
+		Long:  "Get the status of a virtual machine build. The argument should be the ID of the build.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			build, _, err := vmBuilderClient.Get(cmd.Context(), core.VirtualMachineBuildRef{ID: args[0]})
+			if err != nil {
+				if errors.Is(err, core.ErrVirtualMachineBuildNotFound) {
+					return nil, fmt.Errorf("unknown virtual machine build")
+				}
+				return nil, err
+			}
+			return vmBuildOutput(cmd, vmClient, vmBuilderClient, build)
+		}),
+	}
+	addWaitFlags(cmd, defaultVMBuildTimeout)
+	return cmd
 }
 
 // a function for splitting strings by comma but disallowing empty strings.
@@ -391,8 +591,26 @@ func (m mapGetter) Get(key string) string {
 	return m.m[key]
 }
 
+// Used to get a string value which can be set by either a flag or an environment variable. The flag takes priority.
+func flagOrEnv(cmd *cobra.Command, flag string, envs envGetter, env string) string {
+	v, _ := cmd.Flags().GetString(flag)
+	if v == "" {
+		v = envs.Get(env)
+	}
+	return v
+}
+
+// Used to append a string to a slice if it isn't already in it.
+func appendUnique(a []string, s string) []string {
+	if getStringIndex(s, a) == -1 {
+		a = append(a, s)
+	}
+	return a
+}
+
 //nolint:funlen,gocyclo
 func virtualMachinesCreateCmd(
+	vmClient virtualMachinesClient,
 	orgsClient organisationsListClient,
 	dcsClient dataCentersClient,
 	vmPackagesClient virtualMachinePackagesClient,
@@ -413,29 +631,79 @@ func virtualMachinesCreateCmd(
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Allows you to create a VM.",
-		Long:  "Allows you to create a VM.",
-		RunE: func(cmd *cobra.Command, args []string) error {
+		Long: "Allows you to create a VM. Values can be set with flags or environment variables, with flags taking " +
+			"priority. Anything which is not set is asked for interactively unless --no-input is set. " +
+			"Alternatively, a build spec file can be passed with --spec, in which case only the organization is " +
+			"needed. Data centers, packages and disk templates in the spec can be set by permalink or name.",
+		RunE: outputWrapper(func(cmd *cobra.Command, args []string) (Output, error) {
+			// Get all of the values set by flags.
+			flags := cmd.Flags()
+			orgFlag, _ := flags.GetString("org")
+			dcFlag, _ := flags.GetString("dc")
+			packageFlag, _ := flags.GetString("package")
+			vmTemplateFlag, _ := flags.GetString("template")
+			ipFlag, _ := flags.GetStringSlice("ip")
+			sshKeyFlag, _ := flags.GetStringSlice("ssh-key")
+			tagFlag, _ := flags.GetStringSlice("tag")
+			noInput, _ := flags.GetBool("no-input")
+
+			// If a spec file is set, everything comes from that instead.
+			if spec, _ := flags.GetString("spec"); spec != "" {
+				return createVMFromSpecFile(cmd, spec, orgsClient, dcsClient, vmPackagesClient, diskTemplatesClient,
+					vmClient, vmBuilderClient, envs)
+			}
+
+			// Get all of the values set by environment variables.
+			orgNameEnv := envs.Get("KATAPULT_ORG_NAME")
+			orgSubDomainEnv := envs.Get("KATAPULT_ORG_SUBDOMAIN")
+			if orgFlag == "" && orgNameEnv == "" && orgSubDomainEnv == "" {
+				// Fall back to the default organization of the config profile.
+				orgFlag = defaultOrganization
+			}
+			dcNameEnv := envs.Get("KATAPULT_DC_NAME")
+			dcIDEnv := envs.Get("KATAPULT_DC_ID")
+			vmPackageNameEnv := envs.Get("KATAPULT_PACKAGE_NAME")
+			vmPackageIDEnv := envs.Get("KATAPULT_PACKAGE_ID")
+			distributionNameEnv := envs.Get("KATAPULT_DISTRIBUTION_NAME")
+			distributionIDEnv := envs.Get("KATAPULT_DISTRIBUTION_ID")
+
+			// If we can't ask for input, make sure everything required is set before doing anything.
+			if noInput {
+				missing := make([]string, 0, 4)
+				if orgFlag == "" && orgNameEnv == "" && orgSubDomainEnv == "" {
+					missing = append(missing, "organization (--org, KATAPULT_ORG_SUBDOMAIN or KATAPULT_ORG_NAME)")
+				}
+				if dcFlag == "" && dcNameEnv == "" && dcIDEnv == "" {
+					missing = append(missing, "data center (--dc, KATAPULT_DC_ID or KATAPULT_DC_NAME)")
+				}
+				if packageFlag == "" && vmPackageNameEnv == "" && vmPackageIDEnv == "" {
+					missing = append(missing, "package (--package, KATAPULT_PACKAGE_ID or KATAPULT_PACKAGE_NAME)")
+				}
+				if vmTemplateFlag == "" && distributionNameEnv == "" && distributionIDEnv == "" {
+					missing = append(missing,
+						"disk template (--template, KATAPULT_DISTRIBUTION_ID or KATAPULT_DISTRIBUTION_NAME)")
+				}
+				if len(missing) != 0 {
+					return nil, fmt.Errorf("the following values are required when --no-input is set: %s",
+						strings.Join(missing, "; "))
+				}
+			}
+
 			// List the organizations.
 			orgs, _, err := orgsClient.List(cmd.Context())
 			if err != nil {
-				return err
+				return nil, err
 			}
 
-			// Create a fuzzy searcher for organizations.
+			// Select the organization.
 			var org *core.Organization
-			orgNameEnv := envs.Get("KATAPULT_ORG_NAME")
-			orgSubDomainEnv := envs.Get("KATAPULT_ORG_SUBDOMAIN")
-			if orgNameEnv == "" && orgSubDomainEnv == "" {
-				orgRows := make([][]string, len(orgs))
-				for i, potentialOrg := range orgs {
-					orgRows[i] = []string{potentialOrg.Name, potentialOrg.SubDomain}
+			switch {
+			case orgFlag != "":
+				org = findOrganization(orgs, orgFlag)
+				if org == nil {
+					return nil, fmt.Errorf("the organization %s is not attached to your user", orgFlag)
 				}
-				orgArr := console.FuzzyTableSelector(
-					"Which organization would you like to deploy the VM in?",
-					[]string{"Name", "Subdomain"}, orgRows, cmd.InOrStdin(), terminal)
-				index := getArrayIndex(orgArr, orgRows)
-				org = orgs[index]
-			} else {
+			case orgNameEnv != "" || orgSubDomainEnv != "":
 				subdomain := orgNameEnv == ""
 				for _, potentialOrg := range orgs {
 					if subdomain {
@@ -451,31 +719,44 @@ func virtualMachinesCreateCmd(
 					}
 				}
 				if org == nil {
-					return errors.New("the org name/subdomain in your org env variable not attached to your user")
+					return nil, errors.New("the org name/subdomain in your org env variable not attached to your user")
 				}
+			default:
+				// Create a fuzzy searcher for organizations.
+				orgRows := make([][]string, len(orgs))
+				for i, potentialOrg := range orgs {
+					orgRows[i] = []string{potentialOrg.Name, potentialOrg.SubDomain}
+				}
+				orgArr, err := console.FuzzyTableSelector(cmd.Context(),
+					"Which organization would you like to deploy the VM in?",
+					[]string{"Name", "Subdomain"}, orgRows, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
+				index := getArrayIndex(orgArr, orgRows)
+				org = orgs[index]
 			}
 
 			// List the datacenters.
 			dcs, _, err := dcsClient.List(cmd.Context())
 			if err != nil {
-				return err
+				return nil, err
 			}
 
-			// Create a fuzzy searcher for data centers.
-			dcNameEnv := envs.Get("KATAPULT_DC_NAME")
-			dcIDEnv := envs.Get("KATAPULT_DC_ID")
+			// Select the data center.
 			var dc *core.DataCenter
-			if dcNameEnv == "" && dcIDEnv == "" {
-				dcRows := make([][]string, len(dcs))
-				for i, dc := range dcs {
-					dcRows[i] = []string{dc.Name, dc.Country.Name}
+			switch {
+			case dcFlag != "":
+				for _, potentialDC := range dcs {
+					if potentialDC.ID == dcFlag || potentialDC.Permalink == dcFlag || potentialDC.Name == dcFlag {
+						dc = potentialDC
+						break
+					}
 				}
-				dcArr := console.FuzzyTableSelector(
-					"Which DC would you like to deploy the VM in?", []string{"Name", "Country"}, dcRows,
-					cmd.InOrStdin(), terminal)
-				index := getArrayIndex(dcArr, dcRows)
-				dc = dcs[index]
-			} else {
+				if dc == nil {
+					return nil, fmt.Errorf("the data center %s is not attached to your user", dcFlag)
+				}
+			case dcNameEnv != "" || dcIDEnv != "":
 				for _, potentialDC := range dcs {
 					if potentialDC.Name == dcNameEnv || potentialDC.ID == dcIDEnv {
 						dc = potentialDC
@@ -483,19 +764,54 @@ func virtualMachinesCreateCmd(
 					}
 				}
 				if dc == nil {
-					return errors.New("the dc name/id in your dc env variable not attached to your user")
+					return nil, errors.New("the dc name/id in your dc env variable not attached to your user")
+				}
+			default:
+				// Create a fuzzy searcher for data centers.
+				dcRows := make([][]string, len(dcs))
+				for i, dc := range dcs {
+					dcRows[i] = []string{dc.Name, dc.Country.Name}
 				}
+				dcArr, err := console.FuzzyTableSelector(cmd.Context(),
+					"Which DC would you like to deploy the VM in?", []string{"Name", "Country"}, dcRows,
+					cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
+				index := getArrayIndex(dcArr, dcRows)
+				dc = dcs[index]
 			}
 
 			// Select the package.
 			packages, err := listAllVMPackages(cmd.Context(), vmPackagesClient)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			vmPackageNameEnv := envs.Get("KATAPULT_PACKAGE_NAME")
-			vmPackageIDEnv := envs.Get("KATAPULT_PACKAGE_ID")
 			var packageResult *core.VirtualMachinePackage
-			if vmPackageNameEnv == "" && vmPackageIDEnv == "" {
+			switch {
+			case packageFlag != "":
+				for _, potentialPackage := range packages {
+					if potentialPackage.ID == packageFlag || potentialPackage.Permalink == packageFlag ||
+						potentialPackage.Name == packageFlag {
+						packageResult = potentialPackage
+						break
+					}
+				}
+				if packageResult == nil {
+					return nil, fmt.Errorf("the package %s is not attached to your user", packageFlag)
+				}
+			case vmPackageNameEnv != "" || vmPackageIDEnv != "":
+				for _, potentialPackage := range packages {
+					if potentialPackage.Name == vmPackageNameEnv || potentialPackage.ID == vmPackageIDEnv {
+						packageResult = potentialPackage
+						break
+					}
+				}
+				if packageResult == nil {
+					return nil, errors.New(
+						"the package name/slug in your package env variable not attached to your user")
+				}
+			default:
 				packageRows := make([][]string, len(packages))
 				for i, packageItem := range packages {
 					packageRows[i] = []string{
@@ -503,43 +819,37 @@ func virtualMachinesCreateCmd(
 						strconv.Itoa(packageItem.MemoryInGB) + "GB",
 					}
 				}
-				packageArr := console.FuzzyTableSelector(
+				packageArr, err := console.FuzzyTableSelector(cmd.Context(),
 					"Which package would you like to deploy the VM in?",
 					[]string{"Name", "CPU Cores", "Memory"}, packageRows, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
 				index := getArrayIndex(packageArr, packageRows)
 				packageResult = packages[index]
-			} else {
-				for _, potentialPackage := range packages {
-					if potentialPackage.Name == vmPackageNameEnv || potentialPackage.ID == vmPackageIDEnv {
-						packageResult = potentialPackage
-						break
-					}
-				}
-				if packageResult == nil {
-					return errors.New("the package name/slug in your package env variable not attached to your user")
-				}
 			}
 
 			// Ask about the distribution.
 			distributions, err := listAllDiskTemplates(
 				cmd.Context(), core.OrganizationRef{ID: org.ID}, diskTemplatesClient)
 			if err != nil {
-				return err
+				return nil, err
 			}
-			distributionNameEnv := envs.Get("KATAPULT_DISTRIBUTION_NAME")
-			distributionIDEnv := envs.Get("KATAPULT_DISTRIBUTION_ID")
 			var distribution *core.DiskTemplate
-			if distributionNameEnv == "" && distributionIDEnv == "" {
-				distributionStrs := make([]string, len(distributions))
-				for i, distributionItem := range distributions {
-					distributionStrs[i] = distributionItem.Name
+			switch {
+			case vmTemplateFlag != "":
+				for _, potentialDistribution := range distributions {
+					if potentialDistribution.ID == vmTemplateFlag ||
+						potentialDistribution.Permalink == vmTemplateFlag ||
+						potentialDistribution.Name == vmTemplateFlag {
+						distribution = potentialDistribution
+						break
+					}
 				}
-				distributionStr := console.FuzzySelector(
-					"Which distribution would you like to deploy the VM in?",
-					distributionStrs, cmd.InOrStdin(), terminal)
-				index := getStringIndex(distributionStr, distributionStrs)
-				distribution = distributions[index]
-			} else {
+				if distribution == nil {
+					return nil, fmt.Errorf("the disk template %s is not attached to your user", vmTemplateFlag)
+				}
+			case distributionNameEnv != "" || distributionIDEnv != "":
 				for _, potentialDistribution := range distributions {
 					if potentialDistribution.Name == distributionNameEnv ||
 						potentialDistribution.ID == distributionIDEnv {
@@ -548,15 +858,28 @@ func virtualMachinesCreateCmd(
 					}
 				}
 				if distribution == nil {
-					return errors.New("the distribution name/slug in your distribution env variables not " +
+					return nil, errors.New("the distribution name/slug in your distribution env variables not " +
 						"attached to your user")
 				}
+			default:
+				distributionStrs := make([]string, len(distributions))
+				for i, distributionItem := range distributions {
+					distributionStrs[i] = distributionItem.Name
+				}
+				distributionStr, err := console.FuzzySelector(cmd.Context(),
+					"Which distribution would you like to deploy the VM in?",
+					distributionStrs, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
+				index := getStringIndex(distributionStr, distributionStrs)
+				distribution = distributions[index]
 			}
 
 			// Handle networking if there's IP addresses.
 			ips, err := listAllIPAddresses(cmd.Context(), core.OrganizationRef{ID: org.ID}, ipAddressesClient)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			allIps := ips
 			ips = make([]*core.IPAddress, 0)
@@ -567,21 +890,22 @@ func virtualMachinesCreateCmd(
 				}
 			}
 			ipsEnv := envs.Get("KATAPULT_IP_ADDRESSES")
-			if ipsEnv == "" {
-				if len(ips) != 0 {
-					ipRows := make([][]string, len(ips))
-					for i, ip := range ips {
-						ipRows[i] = []string{ip.Address, ip.ReverseDNS}
+			switch {
+			case len(ipFlag) != 0:
+				for _, ipStr := range ipFlag {
+					var selectedIP *core.IPAddress
+					for _, ip := range ips {
+						if ip.Address == ipStr {
+							selectedIP = ip
+							break
+						}
 					}
-					selectedIPRows := console.FuzzyTableMultiSelector(
-						"Please select any IP addresses you wish to add.",
-						[]string{"Address", "Reverse DNS"}, ipRows, cmd.InOrStdin(), terminal)
-					selectedIps = make([]*core.IPAddress, len(selectedIPRows))
-					for i, arr := range selectedIPRows {
-						selectedIps[i] = ips[getArrayIndex(arr, ipRows)]
+					if selectedIP == nil {
+						return nil, fmt.Errorf("the IP address %s is not available to be allocated", ipStr)
 					}
+					selectedIps = append(selectedIps, selectedIP)
 				}
-			} else {
+			case ipsEnv != "":
 				for _, ipStr := range scnz(ipsEnv) {
 					for _, ip := range ips {
 						if ip.Address == ipStr {
@@ -590,116 +914,149 @@ func virtualMachinesCreateCmd(
 						}
 					}
 				}
+			case noInput || len(ips) == 0:
+				// There is nothing to ask.
+			default:
+				ipRows := make([][]string, len(ips))
+				for i, ip := range ips {
+					ipRows[i] = []string{ip.Address, ip.ReverseDNS}
+				}
+				selectedIPRows, err := console.FuzzyTableMultiSelector(cmd.Context(),
+					"Please select any IP addresses you wish to add.",
+					[]string{"Address", "Reverse DNS"}, ipRows, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
+				selectedIps = make([]*core.IPAddress, len(selectedIPRows))
+				for i, arr := range selectedIPRows {
+					selectedIps[i] = ips[getArrayIndex(arr, ipRows)]
+				}
 			}
 
 			// List the SSH keys.
 			keys, err := listAllSSHKeys(cmd.Context(), core.OrganizationRef{ID: org.ID}, sshKeysClient)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			keyIds := []string{}
 			sshKeyIdsEnvSplit := scnz(envs.Get("KATAPULT_SSH_KEY_IDS"))
 			sshKeyNamesEnvSplit := scnz(envs.Get("KATAPULT_SSH_KEY_NAMES"))
 			sshKeyFingerprintsEnvSplit := scnz(envs.Get("KATAPULT_SSH_KEY_FINGERPRINTS"))
-			if len(sshKeyIdsEnvSplit) == 0 && len(sshKeyNamesEnvSplit) == 0 {
-				if len(keys) != 0 {
-					keyRows := make([][]string, len(keys))
-					for i, key := range keys {
-						keyRows[i] = []string{key.Name, key.Fingerprint}
+			switch {
+			case len(sshKeyFlag) != 0:
+				for _, x := range sshKeyFlag {
+					found := false
+					for _, key := range keys {
+						if key.ID == x || key.Name == x || key.Fingerprint == x {
+							keyIds = appendUnique(keyIds, key.ID)
+							found = true
+							break
+						}
 					}
-					selectedKeys := console.FuzzyTableMultiSelector(
-						"Which organization SSH keys do you wish to add?", []string{"Name", "Fingerprint"},
-						keyRows, cmd.InOrStdin(), terminal)
-					keyIds = make([]string, len(selectedKeys))
-					for i, arr := range selectedKeys {
-						keyIds[i] = keys[getArrayIndex(arr, keyRows)].ID
+					if !found {
+						return nil, fmt.Errorf("the SSH key %s doesn't exist", x)
 					}
 				}
-			} else {
+			case len(sshKeyIdsEnvSplit) != 0 || len(sshKeyNamesEnvSplit) != 0 || len(sshKeyFingerprintsEnvSplit) != 0:
 				for _, key := range keys {
-					for _, x := range sshKeyIdsEnvSplit {
-						if key.ID == x {
-							keyIds = append(keyIds, key.ID)
-							goto endOfKeys
-						}
-					}
-
-					for _, x := range sshKeyFingerprintsEnvSplit {
-						if key.Fingerprint == x {
-							keyIds = append(keyIds, key.ID)
-							goto endOfKeys
-						}
-					}
-
-					for _, x := range sshKeyNamesEnvSplit {
-						if key.Name == x {
-							keyIds = append(keyIds, key.ID)
-							goto endOfKeys
-						}
+					if getStringIndex(key.ID, sshKeyIdsEnvSplit) != -1 ||
+						getStringIndex(key.Fingerprint, sshKeyFingerprintsEnvSplit) != -1 ||
+						getStringIndex(key.Name, sshKeyNamesEnvSplit) != -1 {
+						keyIds = append(keyIds, key.ID)
 					}
-
-					// This is more efficient than using a boolean here, even if it looks a bit weird.
-				endOfKeys:
+				}
+			case noInput || len(keys) == 0:
+				// There is nothing to ask.
+			default:
+				keyRows := make([][]string, len(keys))
+				for i, key := range keys {
+					keyRows[i] = []string{key.Name, key.Fingerprint}
+				}
+				selectedKeys, err := console.FuzzyTableMultiSelector(cmd.Context(),
+					"Which organization SSH keys do you wish to add?", []string{"Name", "Fingerprint"},
+					keyRows, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
+				keyIds = make([]string, len(selectedKeys))
+				for i, arr := range selectedKeys {
+					keyIds[i] = keys[getArrayIndex(arr, keyRows)].ID
 				}
 			}
 
 			// Ask for the tags.
 			tags, err := listAllTags(cmd.Context(), core.OrganizationRef{ID: org.ID}, tagsClient)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			tagIds := []string{}
 			tagNamesEnvSplit := scnz(envs.Get("KATAPULT_TAG_NAMES"))
 			tagIdsEnvSplit := scnz(envs.Get("KATAPULT_TAG_IDS"))
-			if len(tagNamesEnvSplit) == 0 && len(tagIdsEnvSplit) == 0 {
-				if len(tags) != 0 {
-					tagStrs := make([]string, len(tags))
-					for i, v := range tags {
-						tagStrs[i] = v.Name
-					}
-					selectedTags := console.FuzzyMultiSelector(
-						"Do you wish to add any tags?", tagStrs, cmd.InOrStdin(), terminal)
-					tagIds = make([]string, len(selectedTags))
-					for i, tagName := range selectedTags {
-						for _, v := range tags {
-							if v.Name == tagName {
-								tagIds[i] = v.ID
-								break
-							}
+			switch {
+			case len(tagFlag) != 0:
+				for _, x := range tagFlag {
+					found := false
+					for _, t := range tags {
+						if t.ID == x || t.Name == x {
+							tagIds = appendUnique(tagIds, t.ID)
+							found = true
+							break
 						}
 					}
+					if !found {
+						return nil, fmt.Errorf("the tag %s doesn't exist", x)
+					}
 				}
-			} else {
+			case len(tagNamesEnvSplit) != 0 || len(tagIdsEnvSplit) != 0:
 				// Go through the tag ID's environment variable.
 				for _, id := range tagIdsEnvSplit {
+					found := false
 					for _, t := range tags {
 						if t.ID == id {
-							// The tag exists. Jump to the end post the error.
-							goto endOfTagIds
+							tagIds = appendUnique(tagIds, t.ID)
+							found = true
+							break
 						}
 					}
-
-					// Handle if a tag doesn't exist.
-					return fmt.Errorf("the tag with the ID %s doesn't exist", id)
-
-					// This is past the error ready for the next iteration.
-				endOfTagIds:
+					if !found {
+						return nil, fmt.Errorf("the tag with the ID %s doesn't exist", id)
+					}
 				}
 
 				// Go through the tag names environment variable.
 				for _, name := range tagNamesEnvSplit {
+					found := false
 					for _, t := range tags {
 						if t.Name == name {
-							// The tag exists. Jump to the end post the error.
-							goto endOfTagNames
+							tagIds = appendUnique(tagIds, t.ID)
+							found = true
+							break
+						}
+					}
+					if !found {
+						return nil, fmt.Errorf("the tag with the name %s doesn't exist", name)
+					}
+				}
+			case noInput || len(tags) == 0:
+				// There is nothing to ask.
+			default:
+				tagStrs := make([]string, len(tags))
+				for i, v := range tags {
+					tagStrs[i] = v.Name
+				}
+				selectedTags, err := console.FuzzyMultiSelector(cmd.Context(),
+					"Do you wish to add any tags?", tagStrs, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
+				tagIds = make([]string, len(selectedTags))
+				for i, tagName := range selectedTags {
+					for _, v := range tags {
+						if v.Name == tagName {
+							tagIds[i] = v.ID
+							break
 						}
 					}
-
-					// Handle if a tag doesn't exist.
-					return fmt.Errorf("the tag with the name %s doesn't exist", name)
-
-					// This is past the error ready for the next iteration.
-				endOfTagNames:
 				}
 			}
 
@@ -714,8 +1071,8 @@ func virtualMachinesCreateCmd(
 			var tracker tracked
 
 			// Check if we need to allow input of the name.
-			name := envs.Get("KATAPULT_NAME")
-			if name == "" {
+			name := flagOrEnv(cmd, "name", envs, "KATAPULT_NAME")
+			if name == "" && !noInput {
 				tracker |= nameTrack
 				fields = append(fields, console.InputField{
 					Optional:    true,
@@ -724,9 +1081,9 @@ func virtualMachinesCreateCmd(
 				})
 			}
 
-			// Check if we need to allow input of the description.
-			hostname := envs.Get("KATAPULT_HOSTNAME")
-			if hostname == "" {
+			// Check if we need to allow input of the hostname.
+			hostname := flagOrEnv(cmd, "hostname", envs, "KATAPULT_HOSTNAME")
+			if hostname == "" && !noInput {
 				tracker |= hostnameTrack
 				fields = append(fields, console.InputField{
 					Optional:    true,
@@ -736,8 +1093,8 @@ func virtualMachinesCreateCmd(
 			}
 
 			// Check if we need to allow input of the description.
-			desc := envs.Get("KATAPULT_DESCRIPTION")
-			if desc == "" {
+			desc := flagOrEnv(cmd, "description", envs, "KATAPULT_DESCRIPTION")
+			if desc == "" && !noInput {
 				tracker |= descriptionTrack
 				fields = append(fields, console.InputField{
 					Optional:    true,
@@ -748,7 +1105,10 @@ func virtualMachinesCreateCmd(
 
 			// Ask for the remainder of the information.
 			if len(fields) != 0 {
-				results := console.MultiInput(fields, cmd.InOrStdin(), terminal)
+				results, err := console.MultiInput(cmd.Context(), fields, cmd.InOrStdin(), terminal)
+				if err != nil {
+					return nil, err
+				}
 				if results == nil {
 					os.Exit(1)
 				}
@@ -773,7 +1133,7 @@ func virtualMachinesCreateCmd(
 			ifaces := make([]*buildspec.NetworkInterface, len(selectedIps))
 			for i, ip := range selectedIps {
 				if ip.Network == nil {
-					return errors.New("ip address not assigned to network")
+					return nil, errors.New("ip address not assigned to network")
 				}
 				ifaces[i] = &buildspec.NetworkInterface{
 					IPAddressAllocations: []*buildspec.IPAddressAllocation{
@@ -802,17 +1162,41 @@ func virtualMachinesCreateCmd(
 				Tags:              tagIds,
 			}
 
+			// If this is a dry run, output the spec instead of building it.
+			if dryRun, _ := flags.GetBool("dry-run"); dryRun {
+				return vmDryRunOutput(cmd, spec, vmCreateCommandLine(
+					cmd, org, dc, packageResult, distribution, selectedIps, keyIds, tagIds, name, hostname, desc))
+			}
+
 			// ✨ Build the virtual machine.
-			// TODO: add wait
-			_, _, err = vmBuilderClient.CreateFromSpec(cmd.Context(), core.OrganizationRef{ID: org.ID}, spec)
+			build, _, err := vmBuilderClient.CreateFromSpec(cmd.Context(), core.OrganizationRef{ID: org.ID}, spec)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
-			// Return no errors.
-			return nil
-		},
+			// Wait for the build if this was requested.
+			return vmBuildOutput(cmd, vmClient, vmBuilderClient, build)
+		}),
 	}
+	addWaitFlags(cmd, defaultVMBuildTimeout)
+	flags := cmd.Flags()
+	flags.String("org", "", "The ID, subdomain or name of the organization to deploy the VM in.")
+	flags.String("dc", "", "The ID, permalink or name of the data center to deploy the VM in.")
+	flags.String("package", "", "The ID, permalink or name of the package to use for the VM.")
+	flags.String("template", "", "The ID, permalink or name of the disk template to use for the VM.")
+	flags.StringSlice("ip", nil, "An IP address to allocate to the VM. Can be specified multiple times.")
+	flags.StringSlice("ssh-key", nil, "The ID, name or fingerprint of an SSH key to add to the VM. "+
+		"Can be specified multiple times.")
+	flags.StringSlice("tag", nil, "The ID or name of a tag to add to the VM. Can be specified multiple times.")
+	flags.String("name", "", "The name of the VM.")
+	flags.String("hostname", "", "The hostname of the VM.")
+	flags.String("description", "", "The description of the VM.")
+	flags.Bool("no-input", false, "Never ask for input. Fails if any required values are not set.")
+	flags.String("spec", "", "Create the VM from a build spec file (XML, YAML or JSON). Use - to read from stdin.")
+	flags.Bool("dry-run", false, "Output the build spec and the equivalent command instead of creating the VM.")
+	flags.String("spec-output", "-", "The file to write the build spec to when using --dry-run. Use - for stdout.")
+	flags.String("spec-format", "", "The format of the build spec written by --dry-run (xml or yaml). "+
+		"Defaults to the extension of --spec-output, or yaml.")
 
 	// Return the command.
 	return cmd
@@ -826,6 +1210,7 @@ func virtualMachinesCmd(vmClient virtualMachinesClient,
 	sshKeysClient sshKeysListClient,
 	tagsClient tagsClient,
 	vmBuilderClient virtualMachinesBuilderClient,
+	tasksClient tasksClient,
 	terminal console.TerminalInterface,
 	envs envGetter) *cobra.Command {
 	cmd := &cobra.Command{
@@ -837,13 +1222,16 @@ func virtualMachinesCmd(vmClient virtualMachinesClient,
 
 	cmd.AddCommand(
 		virtualMachinesListCmd(vmClient),
-		virtualMachinesPoweroffCmd(vmClient),
-		virtualMachinesStartCmd(vmClient),
-		virtualMachinesStopCmd(vmClient),
-		virtualMachinesResetCmd(vmClient),
-		virtualMachinesCreateCmd(orgsClient, dcsClient, vmPackagesClient,
+		virtualMachinesGetCmd(vmClient),
+		virtualMachinesPoweroffCmd(vmClient, tasksClient),
+		virtualMachinesStartCmd(vmClient, tasksClient),
+		virtualMachinesStopCmd(vmClient, tasksClient),
+		virtualMachinesResetCmd(vmClient, tasksClient),
+		virtualMachinesDeleteCmd(vmClient),
+		virtualMachinesCreateCmd(vmClient, orgsClient, dcsClient, vmPackagesClient,
 			diskTemplatesClient, ipAddressesClient, sshKeysClient,
-			tagsClient, vmBuilderClient, terminal, envs))
+			tagsClient, vmBuilderClient, terminal, envs),
+		virtualMachinesBuildStatusCmd(vmClient, vmBuilderClient))
 
 	return cmd
 }
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/krystal/go-katapult/buildspec"
+	"github.com/krystal/go-katapult/core"
+	"github.com/spf13/cobra"
+)
+
+// Defines the flags of vm create which cannot be used alongside --spec since the spec file sets them.
+var specConflictingFlags = []string{
+	"dc", "package", "template", "ip", "ssh-key", "tag", "name", "hostname", "description",
+}
+
+// Used to find an organization by its ID, subdomain or name.
+func findOrganization(orgs []*core.Organization, s string) *core.Organization {
+	for _, org := range orgs {
+		if org.ID == s || org.SubDomain == s || org.Name == s {
+			return org
+		}
+	}
+	return nil
+}
+
+// Used to load a build spec from a file, or stdin if the path is "-". The format is picked from the file
+// extension, falling back to looking at the content when there isn't one.
+func loadVMSpec(cmd *cobra.Command, path string) (*buildspec.VirtualMachineSpec, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = ioutil.ReadAll(cmd.InOrStdin())
+	} else {
+		b, err = ioutil.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var from func(io.Reader) (*buildspec.VirtualMachineSpec, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		from = buildspec.FromXML
+	case ".yaml", ".yml":
+		from = buildspec.FromYAML
+	case ".json":
+		from = buildspec.FromJSON
+	default:
+		trimmed := bytes.TrimSpace(b)
+		switch {
+		case bytes.HasPrefix(trimmed, []byte("<")):
+			from = buildspec.FromXML
+		case bytes.HasPrefix(trimmed, []byte("{")):
+			from = buildspec.FromJSON
+		default:
+			from = buildspec.FromYAML
+		}
+	}
+
+	spec, err := from(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the build spec: %w", err)
+	}
+	return spec, nil
+}
+
+// Used to build the vm create command line which creates a VM with the selections made in the wizard.
+func vmCreateCommandLine(
+	cmd *cobra.Command, org *core.Organization, dc *core.DataCenter, pkg *core.VirtualMachinePackage,
+	tpl *core.DiskTemplate, ips []*core.IPAddress, keyIds, tagIds []string, name, hostname, desc string,
+) string {
+	orDefault := func(s, d string) string {
+		if s == "" {
+			return d
+		}
+		return s
+	}
+	args := []string{
+		"--org", orDefault(org.SubDomain, org.ID),
+		"--dc", orDefault(dc.Permalink, dc.ID),
+		"--package", orDefault(pkg.Permalink, pkg.ID),
+		"--template", orDefault(tpl.Permalink, tpl.ID),
+	}
+	for _, ip := range ips {
+		args = append(args, "--ip", ip.Address)
+	}
+	for _, id := range keyIds {
+		args = append(args, "--ssh-key", id)
+	}
+	for _, id := range tagIds {
+		args = append(args, "--tag", id)
+	}
+	if name != "" {
+		args = append(args, "--name", name)
+	}
+	if hostname != "" {
+		args = append(args, "--hostname", hostname)
+	}
+	if desc != "" {
+		args = append(args, "--description", desc)
+	}
+	return commandLine(cmd.CommandPath(), append(args, "--no-input")...)
+}
+
+// Used to resolve the data center, package and disk template in a build spec to IDs when they are set by
+// permalink or name.
+func resolveVMSpec(
+	ctx context.Context, spec *buildspec.VirtualMachineSpec, org core.OrganizationRef,
+	dcsClient dataCentersClient, vmPackagesClient virtualMachinePackagesClient,
+	diskTemplatesClient virtualMachineDiskTemplatesClient,
+) error {
+	if dc := spec.DataCenter; dc != nil && dc.ID == "" {
+		if dc.Permalink == "" && dc.Name == "" {
+			return errors.New("data_center must be set to an ID, permalink or name in the build spec")
+		}
+		dcs, _, err := dcsClient.List(ctx)
+		if err != nil {
+			return err
+		}
+		for _, potentialDC := range dcs {
+			if (dc.Permalink != "" && potentialDC.Permalink == dc.Permalink) ||
+				(dc.Name != "" && potentialDC.Name == dc.Name) {
+				spec.DataCenter = &buildspec.DataCenter{ID: potentialDC.ID}
+				break
+			}
+		}
+		if spec.DataCenter.ID == "" {
+			return fmt.Errorf("the data center %s in the build spec is not attached to your user",
+				dc.Permalink+dc.Name)
+		}
+	}
+
+	if spec.Resources != nil {
+		if pkg := spec.Resources.Package; pkg != nil && pkg.ID == "" {
+			if pkg.Permalink == "" {
+				return errors.New("resources.package must be set to an ID or permalink in the build spec")
+			}
+			packages, err := listAllVMPackages(ctx, vmPackagesClient)
+			if err != nil {
+				return err
+			}
+			for _, potentialPackage := range packages {
+				if potentialPackage.Permalink == pkg.Permalink || potentialPackage.Name == pkg.Permalink {
+					spec.Resources.Package = &buildspec.Package{ID: potentialPackage.ID}
+					break
+				}
+			}
+			if spec.Resources.Package.ID == "" {
+				return fmt.Errorf("the package %s in the build spec is not attached to your user", pkg.Permalink)
+			}
+		}
+	}
+
+	if tpl := spec.DiskTemplate; tpl != nil && tpl.ID == "" {
+		if tpl.Permalink == "" {
+			return errors.New("disk_template must be set to an ID or permalink in the build spec")
+		}
+		templates, err := listAllDiskTemplates(ctx, org, diskTemplatesClient)
+		if err != nil {
+			return err
+		}
+		for _, potentialTemplate := range templates {
+			if potentialTemplate.Permalink == tpl.Permalink || potentialTemplate.Name == tpl.Permalink {
+				tpl.ID = potentialTemplate.ID
+				tpl.Permalink = ""
+				break
+			}
+		}
+		if tpl.ID == "" {
+			return fmt.Errorf("the disk template %s in the build spec is not attached to your user", tpl.Permalink)
+		}
+	}
+
+	return nil
+}
+
+// Defines the result of a dry run of vm create.
+type vmDryRun struct {
+	Spec    *buildspec.VirtualMachineSpec `json:"spec" yaml:"spec"`
+	File    string                        `json:"file,omitempty" yaml:"file,omitempty"`
+	Command string                        `json:"command" yaml:"command"`
+
+	// Defines the encoded spec. This is only used for text output.
+	Encoded string `json:"-" yaml:"-"`
+}
+
+// Defines the text output of a dry run when the spec is written to a file.
+const vmDryRunFileFormat = `Build spec written to {{ .File }}.
+
+To create the VM without any prompts, run:
+
+  {{ .Command }}
+`
+
+// Defines the text output of a dry run when the spec is written to stdout.
+const vmDryRunStdoutFormat = `{{ .Encoded }}`
+
+// Defines the characters which never need quoting in a shell.
+var shellSafeRegexp = regexp.MustCompile(`^[A-Za-z0-9_./:@%+=,-]+$`)
+
+// Used to quote a string for use as a shell argument.
+func shellQuote(s string) string {
+	if shellSafeRegexp.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Used to build a command line from a command path and arguments.
+func commandLine(path string, args ...string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = shellQuote(arg)
+	}
+	return strings.Join(append([]string{path}, quoted...), " ")
+}
+
+// Used to get the output of a dry run. The spec is written to the file set by --spec-output, or included in the
+// output if that is unset or "-".
+func vmDryRunOutput(cmd *cobra.Command, spec *buildspec.VirtualMachineSpec, command string) (Output, error) {
+	file, _ := cmd.Flags().GetString("spec-output")
+	if file == "-" {
+		file = ""
+	}
+	format, _ := cmd.Flags().GetString("spec-format")
+	if format == "" {
+		format = "yaml"
+		if strings.ToLower(filepath.Ext(file)) == ".xml" {
+			format = "xml"
+		}
+	}
+
+	// Encode the spec.
+	var b []byte
+	var err error
+	switch strings.ToLower(format) {
+	case "xml":
+		b, err = spec.XMLIndent("", "  ")
+		b = append(b, '\n')
+	case "yml", "yaml":
+		b, err = spec.YAML()
+	default:
+		return nil, fmt.Errorf("unknown spec format %s, must be xml or yaml", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &vmDryRun{Spec: spec, File: file, Command: command, Encoded: string(b)}
+	if file != "" {
+		if err := ioutil.WriteFile(file, b, 0o600); err != nil {
+			return nil, err
+		}
+		return &genericOutput{item: result, defaultTextTemplate: vmDryRunFileFormat}, nil
+	}
+
+	// The spec is going to stdout, so put the command on stderr to keep stdout usable as a spec file.
+	if isTextOutput() {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "To create the VM without any prompts, run:\n\n  %s\n", command)
+	}
+	return &genericOutput{item: result, defaultTextTemplate: vmDryRunStdoutFormat}, nil
+}
+
+// Used to create a virtual machine from the build spec file passed with --spec.
+func createVMFromSpecFile(
+	cmd *cobra.Command, path string, orgsClient organisationsListClient, dcsClient dataCentersClient,
+	vmPackagesClient virtualMachinePackagesClient, diskTemplatesClient virtualMachineDiskTemplatesClient,
+	vmClient virtualMachinesClient, vmBuilderClient virtualMachinesBuilderClient, envs envGetter,
+) (Output, error) {
+	for _, name := range specConflictingFlags {
+		if cmd.Flags().Changed(name) {
+			return nil, fmt.Errorf("--%s cannot be used with --spec", name)
+		}
+	}
+
+	// Work out the organization. This isn't part of the build spec, so it must come from a flag or env. Like
+	// the rest of vm create, the name env is used before the subdomain env.
+	orgStr, _ := cmd.Flags().GetString("org")
+	if orgStr == "" {
+		orgStr = envs.Get("KATAPULT_ORG_NAME")
+	}
+	if orgStr == "" {
+		orgStr = envs.Get("KATAPULT_ORG_SUBDOMAIN")
+	}
+	if orgStr == "" {
+		orgStr = defaultOrganization
+	}
+	if orgStr == "" {
+		return nil, fmt.Errorf(
+			"the organization must be set with --org, KATAPULT_ORG_SUBDOMAIN or KATAPULT_ORG_NAME when using --spec")
+	}
+	orgs, _, err := orgsClient.List(cmd.Context())
+	if err != nil {
+		return nil, err
+	}
+	org := findOrganization(orgs, orgStr)
+	if org == nil {
+		return nil, fmt.Errorf("the organization %s is not attached to your user", orgStr)
+	}
+	orgRef := core.OrganizationRef{ID: org.ID}
+
+	// Load the spec and resolve anything which was set by name.
+	spec, err := loadVMSpec(cmd, path)
+	if err != nil {
+		return nil, err
+	}
+	err = resolveVMSpec(cmd.Context(), spec, orgRef, dcsClient, vmPackagesClient, diskTemplatesClient)
+	if err != nil {
+		return nil, err
+	}
+
+	// If this is a dry run, output the resolved spec instead.
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return vmDryRunOutput(cmd, spec, commandLine(cmd.CommandPath(), "--org", orgStr, "--spec", path))
+	}
+
+	// ✨ Build the virtual machine.
+	build, _, err := vmBuilderClient.CreateFromSpec(cmd.Context(), orgRef, spec)
+	if err != nil {
+		return nil, err
+	}
+	return vmBuildOutput(cmd, vmClient, vmBuilderClient, build)
+}
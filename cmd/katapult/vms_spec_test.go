@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krystal/go-katapult/core"
+	"github.com/krystal/katapult-cli/internal/golden"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Defines a YAML build spec which refers to everything by permalink.
+const yamlPermalinkSpec = `data_center:
+  permalink: POG1
+resources:
+  package:
+    permalink: testing
+disk_template:
+  permalink: ubuntu-20-04
+hostname: web-1
+tags:
+  - web
+`
+
+// Defines an XML build spec which refers to everything by ID.
+const xmlIDSpec = `<VirtualMachineSpec>
+  <DataCenter>dc_9UVoPiUQoI1cqtR0</DataCenter>
+  <Resources>
+    <Package>vmpkg_9UVoPiUQoI1cqtRd</Package>
+  </Resources>
+  <DiskTemplate>
+    <DiskTemplate>disk_9UVoPiUQoI1cqtRd</DiskTemplate>
+  </DiskTemplate>
+  <Hostname>
+    <Hostname>web-2</Hostname>
+  </Hostname>
+</VirtualMachineSpec>
+`
+
+func TestVMs_CreateFromSpec(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args     []string
+		envs     map[string]string
+		stdin    string
+		file     string
+		fileName string
+		wantErr  string
+	}{
+		{
+			name:  "yaml from stdin",
+			args:  []string{"--spec", "-", "--org", "loge"},
+			stdin: yamlPermalinkSpec,
+		},
+		{
+			name:     "xml from file",
+			args:     []string{"--org", "test"},
+			file:     xmlIDSpec,
+			fileName: "spec.xml",
+		},
+		{
+			name:     "yaml from file with org env",
+			envs:     map[string]string{"KATAPULT_ORG_SUBDOMAIN": "loge"},
+			file:     yamlPermalinkSpec,
+			fileName: "spec.yml",
+		},
+		{
+			name: "xml from file with org name and subdomain env",
+			envs: map[string]string{
+				"KATAPULT_ORG_NAME":      "testing, testing, 123",
+				"KATAPULT_ORG_SUBDOMAIN": "loge",
+			},
+			file:     xmlIDSpec,
+			fileName: "spec.xml",
+		},
+		{
+			name:  "no organization",
+			args:  []string{"--spec", "-"},
+			stdin: yamlPermalinkSpec,
+			wantErr: "the organization must be set with --org, KATAPULT_ORG_SUBDOMAIN or KATAPULT_ORG_NAME when " +
+				"using --spec",
+		},
+		{
+			name:    "conflicting flag",
+			args:    []string{"--spec", "-", "--org", "loge", "--hostname", "web-3"},
+			stdin:   yamlPermalinkSpec,
+			wantErr: "--hostname cannot be used with --spec",
+		},
+		{
+			name:    "unknown data center",
+			args:    []string{"--spec", "-", "--org", "loge"},
+			stdin:   strings.Replace(yamlPermalinkSpec, "POG1", "NOPE1", 1),
+			wantErr: "the data center NOPE1 in the build spec is not attached to your user",
+		},
+		{
+			name:    "unknown disk template",
+			args:    []string{"--spec", "-", "--org", "loge"},
+			stdin:   strings.Replace(yamlPermalinkSpec, "ubuntu-20-04", "debian-11", 1),
+			wantErr: "the disk template debian-11 in the build spec is not attached to your user",
+		},
+		{
+			name:    "blank data center",
+			args:    []string{"--spec", "-", "--org", "loge"},
+			stdin:   strings.Replace(yamlPermalinkSpec, "data_center:\n  permalink: POG1", "data_center: {}", 1),
+			wantErr: "data_center must be set to an ID, permalink or name in the build spec",
+		},
+		{
+			name:    "blank package",
+			args:    []string{"--spec", "-", "--org", "loge"},
+			stdin:   strings.Replace(yamlPermalinkSpec, "package:\n    permalink: testing", "package: {}", 1),
+			wantErr: "resources.package must be set to an ID or permalink in the build spec",
+		},
+		{
+			name: "blank disk template",
+			args: []string{"--spec", "-", "--org", "loge"},
+			stdin: strings.Replace(yamlPermalinkSpec, "disk_template:\n  permalink: ubuntu-20-04",
+				"disk_template: {}", 1),
+			wantErr: "disk_template must be set to an ID or permalink in the build spec",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"create"}, tt.args...)
+			if tt.file != "" {
+				path := filepath.Join(t.TempDir(), tt.fileName)
+				require.NoError(t, ioutil.WriteFile(path, []byte(tt.file), 0o600))
+				args = append(args, "--spec", path)
+			}
+
+			orgsClient := mockOrganizationsListClient{orgs: fixtureOrganizations}
+			dcsClient := mockDataCentersClient{dcs: fixtureDataCenters}
+			vmPackagesClient := mockVMPackagesClient{packages: successPackages}
+			diskTemplatesClient := mockDiskTemplatesClient{
+				diskTemplates: successDiskTemplates,
+				ref:           core.OrganizationRef{ID: "loge"},
+			}
+			vmBuilderClient := &mockVMBuilderClient{}
+			cmd := virtualMachinesCmd(
+				nil, orgsClient, dcsClient, vmPackagesClient, diskTemplatesClient, nil, nil, nil, vmBuilderClient,
+				nil, nil, mapGetter{m: tt.envs})
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			cmd.SetArgs(args)
+			stdout := assertCobraCommandReturnStdout(t, cmd, tt.wantErr, "")
+			if tt.wantErr != "" {
+				return
+			}
+
+			// Check the output and the spec which was sent to the builder.
+			buf := &bytes.Buffer{}
+			buf.WriteString("-- STDOUT --\n\n")
+			buf.WriteString(stdout)
+			buf.WriteString("\n\n-- BUILD SPEC --\n\n")
+			enc := json.NewEncoder(buf)
+			enc.SetIndent("", "  ")
+			require.NoError(t, enc.Encode(vmBuilderClient))
+			if golden.Update() {
+				golden.Set(t, buf.Bytes())
+			}
+			assert.Equal(t, string(golden.Get(t)), buf.String())
+		})
+	}
+}
+
+func TestVMs_CreateDryRun(t *testing.T) {
+	// Defines the flags which select everything in the wizard.
+	selectFlags := []string{
+		"--org", "loge", "--dc", "POG1", "--package", "testing", "--template", "ubuntu-20-04", "--ip", "1.1.1.1",
+		"--ssh-key", "testing", "--tag", "Testing 2", "--name", "test", "--hostname", "testing",
+		"--description", "it's a test",
+	}
+	wizardCommand := "vm create --org loge --dc POG1 --package testing --template ubuntu-20-04 --ip 1.1.1.1 " +
+		"--ssh-key key_PiUQoI1cqt43Dkf --tag tag_PiUQoI1cqt43geb --name test --hostname testing " +
+		`--description 'it'\''s a test' --no-input`
+
+	tests := []struct {
+		name string
+
+		args     []string
+		output   string
+		stdin    string
+		fileName string
+		stderr   string
+		wantErr  string
+	}{
+		{
+			name:   "yaml to stdout",
+			args:   append([]string{"--dry-run"}, selectFlags...),
+			stderr: "To create the VM without any prompts, run:\n\n  " + wizardCommand + "\n",
+		},
+		{
+			name:   "xml to stdout",
+			args:   append([]string{"--dry-run", "--spec-format", "xml"}, selectFlags...),
+			stderr: "To create the VM without any prompts, run:\n\n  " + wizardCommand + "\n",
+		},
+		{
+			name:     "xml to file",
+			args:     append([]string{"--dry-run"}, selectFlags...),
+			fileName: "spec.xml",
+		},
+		{
+			name:   "json output",
+			args:   append([]string{"--dry-run"}, selectFlags...),
+			output: "json",
+		},
+		{
+			name:   "from spec file",
+			args:   []string{"--dry-run", "--org", "loge", "--spec", "-"},
+			stdin:  yamlPermalinkSpec,
+			stderr: "To create the VM without any prompts, run:\n\n  vm create --org loge --spec -\n",
+		},
+		{
+			name:    "unknown format",
+			args:    append([]string{"--dry-run", "--spec-format", "toml"}, selectFlags...),
+			wantErr: "unknown spec format toml, must be xml or yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"create"}, tt.args...)
+			var path string
+			if tt.fileName != "" {
+				path = filepath.Join(t.TempDir(), tt.fileName)
+				args = append(args, "--spec-output", path)
+			}
+
+			orgsClient := mockOrganizationsListClient{orgs: fixtureOrganizations}
+			dcsClient := mockDataCentersClient{dcs: fixtureDataCenters}
+			vmPackagesClient := mockVMPackagesClient{packages: successPackages}
+			diskTemplatesClient := mockDiskTemplatesClient{
+				diskTemplates: successDiskTemplates,
+				ref:           core.OrganizationRef{ID: "loge"},
+			}
+			ipAddressesClient := mockIPAddressClient{organizationIDPages: successIPPages}
+			sshKeysClient := mockSSHKeysClient{organizationIDPages: successKeyPages}
+			tagsClient := mockTagsClient{organizationIDPages: successTagPages}
+			vmBuilderClient := &mockVMBuilderClient{}
+			cmd := virtualMachinesCmd(
+				nil, orgsClient, dcsClient, vmPackagesClient, diskTemplatesClient, ipAddressesClient,
+				sshKeysClient, tagsClient, vmBuilderClient, nil, nil, mapGetter{})
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			cmd.SetArgs(args)
+			outputFlag = tt.output
+			stdout := assertCobraCommandReturnStdout(t, cmd, tt.wantErr, tt.stderr)
+			outputFlag = ""
+			if tt.wantErr != "" {
+				return
+			}
+
+			// Nothing should have been built.
+			assert.Nil(t, vmBuilderClient.SpecResult)
+
+			// Check the output and the file which was written.
+			buf := &bytes.Buffer{}
+			buf.WriteString("-- STDOUT --\n\n")
+			if path != "" {
+				stdout = strings.ReplaceAll(stdout, path, "<file>")
+			}
+			buf.WriteString(stdout)
+			if path != "" {
+				b, err := ioutil.ReadFile(path)
+				require.NoError(t, err)
+				buf.WriteString("\n-- FILE --\n\n")
+				buf.Write(b)
+			}
+			if golden.Update() {
+				golden.Set(t, buf.Bytes())
+			}
+			assert.Equal(t, string(golden.Get(t)), buf.String())
+		})
+	}
+}
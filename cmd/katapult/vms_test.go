@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/augurysys/timestamp"
 	"github.com/krystal/go-katapult/buildspec"
@@ -36,6 +38,9 @@ type vmsClient struct {
 
 	// Defines the organization subdomain -> vmPages.
 	organizationSubdomainPages map[string]vmPages
+
+	// Defines the ID's/FQDN's of virtual machines which were deleted.
+	deleted map[string]bool
 }
 
 // Used to toggle the power state and return the old result.
@@ -89,6 +94,26 @@ func (v *vmsClient) List(_ context.Context, org core.OrganizationRef, opts *core
 	}}, nil
 }
 
+func (v *vmsClient) Get(_ context.Context, ref core.VirtualMachineRef) (
+	*core.VirtualMachine, *katapult.Response, error,
+) {
+	if err := v.ensureFound(ref); err != nil {
+		return nil, nil, err
+	}
+	for _, pageSet := range []map[string]vmPages{v.organizationIDPages, v.organizationSubdomainPages} {
+		for _, pages := range pageSet {
+			for _, page := range pages {
+				for _, vm := range page {
+					if (ref.ID != "" && vm.ID == ref.ID) || (ref.ID == "" && vm.FQDN == ref.FQDN) {
+						return vm, nil, nil
+					}
+				}
+			}
+		}
+	}
+	return nil, nil, core.ErrVirtualMachineNotFound
+}
+
 func (v *vmsClient) ensureFound(ref core.VirtualMachineRef) error {
 	if (ref.FQDN != "" && v.fqdnNotFound == ref.FQDN) || (ref.ID != "" && v.idNotFound == ref.ID) {
 		return core.ErrVirtualMachineNotFound
@@ -119,7 +144,7 @@ func (v *vmsClient) Shutdown(_ context.Context, ref core.VirtualMachineRef) (*co
 			Description: "VM was not powered on",
 		})
 	}
-	return &core.Task{}, nil, nil
+	return &core.Task{ID: "task_" + id, Status: core.TaskPending}, nil, nil
 }
 
 func (v *vmsClient) Stop(_ context.Context, ref core.VirtualMachineRef) (*core.Task, *katapult.Response, error) {
@@ -150,7 +175,7 @@ func (v *vmsClient) Start(_ context.Context, ref core.VirtualMachineRef) (*core.
 			Description: "VM was powered on",
 		})
 	}
-	return &core.Task{}, nil, nil
+	return &core.Task{ID: "task_" + id, Status: core.TaskPending}, nil, nil
 }
 
 func (v *vmsClient) Reset(_ context.Context, ref core.VirtualMachineRef) (*core.Task, *katapult.Response, error) {
@@ -177,7 +202,7 @@ func (v *vmsClient) Reset(_ context.Context, ref core.VirtualMachineRef) (*core.
 		})
 	}
 	v.togglePowerState(id, fqdn)
-	return nil, nil, nil
+	return &core.Task{ID: "task_" + id, Status: core.TaskPending}, nil, nil
 }
 
 func (v *vmsClient) Delete(_ context.Context, ref core.VirtualMachineRef) (
@@ -188,13 +213,25 @@ func (v *vmsClient) Delete(_ context.Context, ref core.VirtualMachineRef) (
 		return nil, nil, err
 	}
 
+	var objectID string
 	switch {
 	case ref.ID != "":
 		delete(v.powerStates, "i"+ref.ID)
+		objectID = ref.ID
 	case ref.FQDN != "":
 		delete(v.powerStates, "s"+ref.FQDN)
+		objectID = ref.FQDN
+	}
+	if v.deleted == nil {
+		v.deleted = map[string]bool{}
 	}
-	return nil, nil, nil
+	v.deleted[objectID] = true
+	return &core.TrashObject{
+		ID:         "trsh_" + objectID,
+		KeepUntil:  timestamp.Unix(mockNow.Add(7*24*time.Hour).Unix(), 0),
+		ObjectID:   objectID,
+		ObjectType: "VirtualMachine",
+	}, nil, nil
 }
 
 func TestVMs_List(t *testing.T) {
@@ -297,13 +334,141 @@ func TestVMs_List(t *testing.T) {
 			cmd := virtualMachinesCmd(
 				&vmsClient{organizationIDPages: tt.id, organizationSubdomainPages: tt.subdomains}, nil,
 				nil, nil, nil, nil, nil,
-				nil, nil, nil, nil)
+				nil, nil, nil, nil, nil)
 			cmd.SetArgs(tt.args)
 			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
 		})
 	}
 }
 
+func TestVMs_ListColumns(t *testing.T) {
+	tests := []struct {
+		name string
+
+		output    string
+		columns   []string
+		noHeaders bool
+		wantErr   string
+	}{
+		{
+			name:   "wide",
+			output: "wide",
+		},
+		{
+			name:    "selected columns",
+			columns: []string{"name", "state", "package.name", "zone.data_center.name", "created_at"},
+		},
+		{
+			name:    "selected columns csv",
+			output:  "csv",
+			columns: []string{"id", "fqdn", "package.permalink"},
+		},
+		{
+			name:      "no headers",
+			noHeaders: true,
+		},
+		{
+			name:      "no headers tsv",
+			output:    "tsv",
+			noHeaders: true,
+		},
+		{
+			name:    "unknown column",
+			columns: []string{"name", "colour"},
+			wantErr: "unknown column colour",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The second virtual machine is missing most fields to check they are blank.
+			pages := map[string]vmPages{"loge": {{fixtureVirtualMachine, {ID: "vm_2", Name: "Empty"}}}}
+			cmd := virtualMachinesCmd(
+				&vmsClient{organizationSubdomainPages: pages}, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd.SetArgs([]string{"list", "loge"})
+			outputFlag = tt.output
+			columnsFlag = tt.columns
+			noHeadersFlag = tt.noHeaders
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			outputFlag = ""
+			columnsFlag = nil
+			noHeadersFlag = false
+		})
+	}
+}
+
+var fixtureVirtualMachine = &core.VirtualMachine{
+	ID:          "vm_rrmEoG6CKUX0IKgX",
+	Name:        "My Blog",
+	Hostname:    "my-blog",
+	FQDN:        "my-blog.acme-labs.katapult.cloud",
+	Description: "test",
+	CreatedAt:   timestamp.Unix(mockNow.Unix(), 0),
+	State:       core.VirtualMachineStarted,
+	Zone: &core.Zone{
+		ID:         "zone_1",
+		Name:       "North West",
+		Permalink:  "north-west",
+		DataCenter: fixtureDataCenters[1],
+	},
+	Package:  &core.VirtualMachinePackage{ID: "vmpkg_1", Name: "Rock 3", Permalink: "rock-3"},
+	TagNames: []string{"production", "blog"},
+	IPAddresses: []*core.IPAddress{
+		{ID: "ip_1", Address: "1.1.1.1"},
+		{ID: "ip_2", Address: "2a03:2800::1"},
+	},
+}
+
+func TestVMs_Get(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		output  string
+		wantErr string
+	}{
+		{
+			name:    "no ID/FQDN provided",
+			args:    []string{"get"},
+			wantErr: "both ID and FQDN are unset",
+		},
+		{
+			name: "test get by ID",
+			args: []string{"get", "--id=vm_rrmEoG6CKUX0IKgX"},
+		},
+		{
+			name: "test get by FQDN",
+			args: []string{"get", "--fqdn=my-blog.acme-labs.katapult.cloud"},
+		},
+		{
+			name:   "test get json",
+			args:   []string{"get", "--id=vm_rrmEoG6CKUX0IKgX"},
+			output: "json",
+		},
+		{
+			name:   "test get yaml",
+			args:   []string{"get", "--id=vm_rrmEoG6CKUX0IKgX"},
+			output: "yaml",
+		},
+		{
+			name:    "test id not found",
+			args:    []string{"get", "--id=not_exists"},
+			wantErr: "unknown virtual machine",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &vmsClient{organizationIDPages: map[string]vmPages{
+				"1": {{fixtureVirtualMachine}},
+			}}
+			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd.SetArgs(tt.args)
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			outputFlag = ""
+		})
+	}
+}
+
 func TestVMs_Poweroff(t *testing.T) {
 	tests := []struct {
 		name string
@@ -390,7 +555,7 @@ func TestVMs_Poweroff(t *testing.T) {
 			if tt.poweredDown != nil {
 				client.togglePowerState(tt.poweredDown.key, tt.poweredDown.fqdn)
 			}
-			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			cmd.SetArgs(tt.args)
 			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
 			if tt.validate != nil {
@@ -486,7 +651,7 @@ func TestVMs_Stop(t *testing.T) {
 			if tt.poweredDown != nil {
 				client.togglePowerState(tt.poweredDown.key, tt.poweredDown.fqdn)
 			}
-			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			cmd.SetArgs(tt.args)
 			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
 			if tt.validate != nil {
@@ -582,7 +747,7 @@ func TestVMs_Start(t *testing.T) {
 			if tt.poweredDown != nil {
 				client.togglePowerState(tt.poweredDown.key, tt.poweredDown.fqdn)
 			}
-			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			cmd.SetArgs(tt.args)
 			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
 			if tt.validate != nil {
@@ -678,7 +843,7 @@ func TestVMs_Reset(t *testing.T) {
 			if tt.poweredDown != nil {
 				client.togglePowerState(tt.poweredDown.key, tt.poweredDown.fqdn)
 			}
-			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			cmd.SetArgs(tt.args)
 			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
 			if tt.validate != nil {
@@ -688,6 +853,157 @@ func TestVMs_Reset(t *testing.T) {
 	}
 }
 
+func TestVMs_Delete(t *testing.T) {
+	tests := []struct {
+		name string
+
+		idNotFound   string
+		fqdnNotFound string
+
+		args    []string
+		output  string
+		stdin   string
+		deleted string
+		stderr  string
+		wantErr string
+	}{
+		{
+			name:    "no ID/FQDN provided",
+			args:    []string{"delete", "--yes"},
+			wantErr: "both ID and FQDN are unset",
+		},
+		{
+			name:    "test delete by ID",
+			args:    []string{"delete", "--id=1", "--yes"},
+			deleted: "1",
+		},
+		{
+			name:    "test delete by FQDN",
+			args:    []string{"delete", "--fqdn=test.example.com", "--yes"},
+			deleted: "test.example.com",
+		},
+		{
+			name:    "test delete json",
+			args:    []string{"delete", "--id=1", "-y"},
+			output:  "json",
+			deleted: "1",
+		},
+		{
+			name:    "test delete confirmed",
+			args:    []string{"delete", "--id=1"},
+			stdin:   "y\n",
+			stderr:  "\x1b[36mAre you sure you want to delete the virtual machine 1? [y/N]\x1b[0m ",
+			deleted: "1",
+		},
+		{
+			name:    "test delete declined",
+			args:    []string{"delete", "--id=1"},
+			stdin:   "n\n",
+			wantErr: "deletion cancelled",
+		},
+		{
+			name:       "test id not found",
+			idNotFound: "not_exists",
+			args:       []string{"delete", "--id=not_exists", "--yes"},
+			wantErr:    "unknown virtual machine",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeNow = func() time.Time { return mockNow }
+			client := &vmsClient{fqdnNotFound: tt.fqdnNotFound, idNotFound: tt.idNotFound}
+			cmd := virtualMachinesCmd(client, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			cmd.SetArgs(tt.args)
+			cmd.SetIn(strings.NewReader(tt.stdin))
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, tt.stderr)
+			outputFlag = ""
+			timeNow = time.Now
+			if tt.deleted != "" {
+				assert.True(t, client.deleted[tt.deleted])
+			} else {
+				assert.Empty(t, client.deleted)
+			}
+		})
+	}
+}
+
+func TestVMs_BuildStatus(t *testing.T) {
+	tests := []struct {
+		name string
+
+		args    []string
+		states  []core.VirtualMachineBuildState
+		output  string
+		wantErr string
+	}{
+		{
+			name:    "no build ID provided",
+			args:    []string{"build-status"},
+			wantErr: "accepts 1 arg(s), received 0",
+		},
+		{
+			name:   "pending build",
+			args:   []string{"build-status", "vmbuild_1"},
+			states: []core.VirtualMachineBuildState{core.VirtualMachineBuildPending},
+		},
+		{
+			name:   "complete build",
+			args:   []string{"build-status", "vmbuild_1"},
+			states: []core.VirtualMachineBuildState{core.VirtualMachineBuildComplete},
+		},
+		{
+			name: "wait for build",
+			args: []string{"build-status", "vmbuild_1", "--wait"},
+			states: []core.VirtualMachineBuildState{
+				core.VirtualMachineBuildPending, core.VirtualMachineBuildBuilding, core.VirtualMachineBuildComplete,
+			},
+		},
+		{
+			name: "wait for build json",
+			args: []string{"build-status", "vmbuild_1", "--wait"},
+			states: []core.VirtualMachineBuildState{
+				core.VirtualMachineBuildBuilding, core.VirtualMachineBuildComplete,
+			},
+			output: "json",
+		},
+		{
+			name: "wait for failed build",
+			args: []string{"build-status", "vmbuild_1", "--wait"},
+			states: []core.VirtualMachineBuildState{
+				core.VirtualMachineBuildBuilding, core.VirtualMachineBuildFailed,
+			},
+			wantErr: "virtual machine build vmbuild_1 failed",
+		},
+		{
+			name:    "wait timeout",
+			args:    []string{"build-status", "vmbuild_1", "--wait", "--wait-timeout=20ms"},
+			states:  []core.VirtualMachineBuildState{core.VirtualMachineBuildBuilding},
+			wantErr: "timed out waiting for virtual machine build vmbuild_1 to finish",
+		},
+		{
+			name:    "unknown build",
+			args:    []string{"build-status", "vmbuild_2"},
+			wantErr: "unknown virtual machine build",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pollInterval = time.Millisecond
+			defer func() { pollInterval = 2 * time.Second }()
+			vmClient := &vmsClient{organizationIDPages: map[string]vmPages{
+				"1": {{fixtureVirtualMachine}},
+			}}
+			builder := &mockVMBuilderClient{states: tt.states, vm: fixtureVirtualMachine}
+			cmd := virtualMachinesCmd(vmClient, nil, nil, nil, nil, nil, nil, nil, builder, nil, nil, nil)
+			cmd.SetArgs(tt.args)
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			outputFlag = ""
+		})
+	}
+}
+
 type sshPages [][]*core.AuthSSHKey
 
 type mockSSHKeysClient struct {
@@ -881,6 +1197,12 @@ type mockVMBuilderClient struct {
 
 	OrgResult  core.OrganizationRef
 	SpecResult *buildspec.VirtualMachineSpec
+
+	// Defines the states returned by each call to Get. The last state is repeated.
+	states []core.VirtualMachineBuildState
+
+	// Defines the virtual machine which is attached to the build once it is complete.
+	vm *core.VirtualMachine
 }
 
 func (m *mockVMBuilderClient) CreateFromSpec(_ context.Context, org core.OrganizationRef,
@@ -890,7 +1212,22 @@ func (m *mockVMBuilderClient) CreateFromSpec(_ context.Context, org core.Organiz
 	}
 	m.OrgResult = org
 	m.SpecResult = spec
-	return nil, nil, nil
+	return &core.VirtualMachineBuild{ID: "vmbuild_1", State: core.VirtualMachineBuildPending}, nil, nil
+}
+
+func (m *mockVMBuilderClient) Get(_ context.Context, ref core.VirtualMachineBuildRef) (
+	*core.VirtualMachineBuild, *katapult.Response, error) {
+	if ref.ID != "vmbuild_1" || len(m.states) == 0 {
+		return nil, nil, core.ErrVirtualMachineBuildNotFound
+	}
+	build := &core.VirtualMachineBuild{ID: ref.ID, State: m.states[0]}
+	if len(m.states) > 1 {
+		m.states = m.states[1:]
+	}
+	if build.State == core.VirtualMachineBuildComplete && m.vm != nil {
+		build.VirtualMachine = &core.VirtualMachine{ID: m.vm.ID, FQDN: m.vm.FQDN}
+	}
+	return build, nil, nil
 }
 
 var successPackages = []*core.VirtualMachinePackage{
@@ -1094,6 +1431,7 @@ func TestVMs_Create(t *testing.T) {
 	tests := []struct {
 		name string
 
+		args []string
 		envs map[string]string
 
 		orgs       []*core.Organization
@@ -1519,6 +1857,123 @@ func TestVMs_Create(t *testing.T) {
 				keystrokes.Enter,
 			},
 		},
+		{
+			name: "success from flags",
+			args: []string{
+				"--org", "loge", "--dc", "POG1", "--package", "testing", "--template", "ubuntu-20-04",
+				"--ip", "1.1.1.1", "--ip", "1.1.1.2", "--ssh-key", "testing", "--ssh-key", "key_PiUQoI1cqt43Dke",
+				"--tag", "Testing 2", "--tag", "tag_PiUQoI1cqt43gei", "--name", "test", "--hostname", "testing",
+				"--description", "123",
+			},
+			orgs:          fixtureOrganizations,
+			dcs:           fixtureDataCenters,
+			packages:      successPackages,
+			expectedRef:   core.OrganizationRef{ID: "loge"},
+			diskTemplates: successDiskTemplates,
+			ipIDPages:     successIPPages,
+			keysIDPages:   successKeyPages,
+			tagIDPages:    successTagPages,
+		},
+		{
+			name: "flags override env",
+			args: []string{"--org", "test", "--dc", "GB1", "--name", "flag", "--no-input"},
+			envs: map[string]string{
+				"KATAPULT_ORG_SUBDOMAIN":   "loge",
+				"KATAPULT_DC_ID":           "dc_9UVoPiUQoI1cqtRd",
+				"KATAPULT_PACKAGE_ID":      "vmpkg_9UVoPiUQoI1cqtRd",
+				"KATAPULT_DISTRIBUTION_ID": "disk_9UVoPiUQoI1cqtRd",
+				"KATAPULT_NAME":            "env",
+			},
+			orgs:          fixtureOrganizations,
+			dcs:           fixtureDataCenters,
+			packages:      successPackages,
+			expectedRef:   core.OrganizationRef{ID: "testing"},
+			diskTemplates: successDiskTemplates,
+			ipIDPages:     successIPPages,
+			keysIDPages:   successKeyPages,
+			tagIDPages:    successTagPages,
+		},
+		{
+			name: "success with no input",
+			args: []string{
+				"--org", "loge", "--dc", "dc_9UVoPiUQoI1cqtRd", "--package", "Test", "--template", "Ubuntu 20.04",
+				"--no-input",
+			},
+			orgs:          fixtureOrganizations,
+			dcs:           fixtureDataCenters,
+			packages:      successPackages,
+			expectedRef:   core.OrganizationRef{ID: "loge"},
+			diskTemplates: successDiskTemplates,
+			ipIDPages:     successIPPages,
+			keysIDPages:   successKeyPages,
+			tagIDPages:    successTagPages,
+		},
+		{
+			name: "success with tag env",
+			args: []string{
+				"--org", "loge", "--dc", "dc_9UVoPiUQoI1cqtRd", "--package", "Test", "--template", "Ubuntu 20.04",
+				"--no-input",
+			},
+			envs: map[string]string{
+				"KATAPULT_TAG_IDS":   "tag_PiUQoI1cqt43gea",
+				"KATAPULT_TAG_NAMES": "Testing 2,Testing 1",
+			},
+			orgs:          fixtureOrganizations,
+			dcs:           fixtureDataCenters,
+			packages:      successPackages,
+			expectedRef:   core.OrganizationRef{ID: "loge"},
+			diskTemplates: successDiskTemplates,
+			ipIDPages:     successIPPages,
+			keysIDPages:   successKeyPages,
+			tagIDPages:    successTagPages,
+		},
+
+		// Flag errors
+
+		{
+			name: "no input with missing values",
+			args: []string{"--org", "loge", "--no-input"},
+			envs: map[string]string{"KATAPULT_PACKAGE_ID": "vmpkg_9UVoPiUQoI1cqtRd"},
+			wantErr: "the following values are required when --no-input is set: " +
+				"data center (--dc, KATAPULT_DC_ID or KATAPULT_DC_NAME); " +
+				"disk template (--template, KATAPULT_DISTRIBUTION_ID or KATAPULT_DISTRIBUTION_NAME)",
+		},
+		{
+			name:    "unknown org flag",
+			args:    []string{"--org", "unknown"},
+			orgs:    fixtureOrganizations,
+			wantErr: "the organization unknown is not attached to your user",
+		},
+		{
+			name: "unavailable ip flag",
+			args: []string{
+				"--org", "loge", "--dc", "POG1", "--package", "testing", "--template", "ubuntu-20-04",
+				"--ip", "9.9.9.9",
+			},
+			orgs:          fixtureOrganizations,
+			dcs:           fixtureDataCenters,
+			packages:      successPackages,
+			expectedRef:   core.OrganizationRef{ID: "loge"},
+			diskTemplates: successDiskTemplates,
+			ipIDPages:     successIPPages,
+			wantErr:       "the IP address 9.9.9.9 is not available to be allocated",
+		},
+		{
+			name: "unknown tag flag",
+			args: []string{
+				"--org", "loge", "--dc", "POG1", "--package", "testing", "--template", "ubuntu-20-04",
+				"--tag", "unknown", "--no-input",
+			},
+			orgs:          fixtureOrganizations,
+			dcs:           fixtureDataCenters,
+			packages:      successPackages,
+			expectedRef:   core.OrganizationRef{ID: "loge"},
+			diskTemplates: successDiskTemplates,
+			ipIDPages:     successIPPages,
+			keysIDPages:   successKeyPages,
+			tagIDPages:    successTagPages,
+			wantErr:       "the tag unknown doesn't exist",
+		},
 
 		// Client error throwing
 
@@ -1740,10 +2195,10 @@ func TestVMs_Create(t *testing.T) {
 			// Create the command.
 			cmd := virtualMachinesCmd(
 				nil, orgsClient, dcsClient, vmPackagesClient, diskTemplatesClient,
-				ipAddressesClient, sshKeysClient, tags, vmBuilderClient, mockTerminal,
+				ipAddressesClient, sshKeysClient, tags, vmBuilderClient, nil, mockTerminal,
 				mapGetter{m: tt.envs})
 			cmd.SetIn(stdin)
-			cmd.SetArgs([]string{"create"})
+			cmd.SetArgs(append([]string{"create"}, tt.args...))
 			stdout := assertCobraCommandReturnStdout(t, cmd, tt.wantErr, tt.stderr)
 
 			// Create the resulting golden data and handle it.
@@ -1764,3 +2219,69 @@ func TestVMs_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestVMs_CreateWait(t *testing.T) {
+	tests := []struct {
+		name string
+
+		states  []core.VirtualMachineBuildState
+		output  string
+		wantErr string
+	}{
+		{
+			name: "wait for build",
+			states: []core.VirtualMachineBuildState{
+				core.VirtualMachineBuildBuilding, core.VirtualMachineBuildComplete,
+			},
+		},
+		{
+			name: "wait for build yaml",
+			states: []core.VirtualMachineBuildState{
+				core.VirtualMachineBuildComplete,
+			},
+			output: "yaml",
+		},
+		{
+			name: "wait for failed build",
+			states: []core.VirtualMachineBuildState{
+				core.VirtualMachineBuildPending, core.VirtualMachineBuildFailed,
+			},
+			wantErr: "virtual machine build vmbuild_1 failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pollInterval = time.Millisecond
+			defer func() { pollInterval = 2 * time.Second }()
+			vmClient := &vmsClient{organizationIDPages: map[string]vmPages{
+				"1": {{fixtureVirtualMachine}},
+			}}
+			builder := &mockVMBuilderClient{states: tt.states, vm: fixtureVirtualMachine}
+			cmd := virtualMachinesCmd(
+				vmClient, mockOrganizationsListClient{orgs: fixtureOrganizations},
+				mockDataCentersClient{dcs: fixtureDataCenters},
+				mockVMPackagesClient{packages: successPackages},
+				mockDiskTemplatesClient{diskTemplates: successDiskTemplates, ref: core.OrganizationRef{ID: "loge"}},
+				mockIPAddressClient{organizationIDPages: successIPPages},
+				mockSSHKeysClient{organizationIDPages: successKeyPages},
+				mockTagsClient{organizationIDPages: successTagPages},
+				builder, nil, &console.MockTerminal{},
+				mapGetter{m: map[string]string{
+					"KATAPULT_ORG_SUBDOMAIN":   "loge",
+					"KATAPULT_DC_ID":           "dc_9UVoPiUQoI1cqtRd",
+					"KATAPULT_PACKAGE_ID":      "vmpkg_9UVoPiUQoI1cqtRd",
+					"KATAPULT_DISTRIBUTION_ID": "disk_9UVoPiUQoI1cqtRd",
+					"KATAPULT_IP_ADDRESSES":    "1.1.1.1",
+					"KATAPULT_SSH_KEY_IDS":     "key_PiUQoI1cqt43Dkc",
+					"KATAPULT_TAG_IDS":         "tag_PiUQoI1cqt43gea",
+					"KATAPULT_NAME":            "test",
+					"KATAPULT_HOSTNAME":        "testing",
+					"KATAPULT_DESCRIPTION":     "123",
+				}})
+			cmd.SetArgs([]string{"create", "--wait"})
+			outputFlag = tt.output
+			assertCobraCommand(t, cmd, tt.wantErr, "")
+			outputFlag = ""
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/krystal/katapult-cli/cmd/katapult/console"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Defines how often things that are being waited on are polled. This is a variable so that it can be lowered in tests.
+var pollInterval = 2 * time.Second
+
+// Returned by pollUntil when --wait-timeout is reached, and by run when --timeout is. Either way, the CLI exits
+// with exitTimeout.
+var errWaitTimeout = errors.New("timed out")
+
+// Used to add the flags used to wait for an action to finish.
+func addWaitFlags(cmd *cobra.Command, defaultTimeout time.Duration) {
+	flags := cmd.Flags()
+	flags.Bool("wait", false, "Wait for the action to finish before exiting.")
+	flags.Duration("wait-timeout", defaultTimeout,
+		"The maximum time to wait for the action to finish when using --wait.")
+}
+
+// Used to check if --wait was set.
+func waitWanted(cmd *cobra.Command) bool {
+	wait, _ := cmd.Flags().GetBool("wait")
+	return wait
+}
+
+// Used to get a context for waiting which is cancelled after the duration in --wait-timeout.
+func waitContext(cmd *cobra.Command) (context.Context, context.CancelFunc) {
+	if timeout, _ := cmd.Flags().GetDuration("wait-timeout"); timeout > 0 {
+		return context.WithTimeout(cmd.Context(), timeout)
+	}
+	return context.WithCancel(cmd.Context())
+}
+
+// Used to check if the writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+// Used to start a spinner on stdout if it is a terminal. Returns a function to update the message and a function
+// to stop the spinner. Both are no-ops if stdout is not a terminal.
+func progressSpinner(cmd *cobra.Command, message string) (func(message string), func()) {
+	out := cmd.OutOrStdout()
+	if !isTerminal(out) {
+		return func(string) {}, func() {}
+	}
+	spinner := console.NewSpinner(out, message)
+	spinner.Start()
+	return spinner.SetMessage, spinner.Stop
+}
+
+// Used to call the check function every poll interval until it returns true or an error.
+// Returns errWaitTimeout if the context deadline is exceeded.
+func pollUntil(ctx context.Context, check func(ctx context.Context) (bool, error)) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check(ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return errWaitTimeout
+			}
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return errWaitTimeout
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -2,39 +2,136 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// DefaultProfile is the name of the profile which uses the top level settings in the config file.
+const DefaultProfile = "default"
+
 type Config struct {
+	// Defines the settings from all sources. This is what the config is loaded from.
 	viper *viper.Viper
 
-	APIURL   string `mapstructure:"api_url"`
-	APIToken string `mapstructure:"api_token"`
+	// Defines only the settings which are in the config file. This is what gets written, so that values from
+	// flags and environment variables don't end up in the file.
+	file *viper.Viper
+
+	// Defines the flags which keys are bound to.
+	flags map[string]*pflag.Flag
+
+	// Defines the errors from applying the active profile and getting the token from its credential store when
+	// the config was loaded.
+	profileErr error
+	tokenErr   error
+
+	APIURL       string `mapstructure:"api_url"`
+	APIToken     string `mapstructure:"api_token"`
+	Organization string `mapstructure:"organization"`
+
+	// CredentialStore is the name of the store which the API token is kept in. If this is blank, the token is in
+	// the config file.
+	CredentialStore string `mapstructure:"credential_store"`
+
+	// CredentialHelper is the command which is run by the helper credential store.
+	CredentialHelper string `mapstructure:"credential_helper"`
+
+	// Retries is the number of times a rate limited or failed request is retried.
+	Retries string `mapstructure:"retries"`
+
+	// RetryMaxWait is the longest time to wait between retries, such as 30s.
+	RetryMaxWait string `mapstructure:"retry_max_wait"`
+
+	// HTTPSProxy is the URL of the proxy which requests to the API are sent through. If this is blank, the
+	// HTTPS_PROXY environment variable is used.
+	HTTPSProxy string `mapstructure:"https_proxy"`
+
+	// CABundle is the path of a PEM file with extra certificate authorities to trust, such as a corporate CA.
+	CABundle string `mapstructure:"ca_bundle"`
+
+	// ClientCert and ClientKey are the paths of the PEM files of a TLS client certificate and its key.
+	ClientCert string `mapstructure:"client_cert"`
+	ClientKey  string `mapstructure:"client_key"`
+
+	// InsecureSkipVerify turns off checking the certificate of the API when set to true.
+	InsecureSkipVerify string `mapstructure:"insecure_skip_verify"`
+
+	// Profile is the profile which was selected with a flag or environment variable.
+	Profile string `mapstructure:"profile"`
+
+	// CurrentProfile is the profile which was selected with "config use-profile".
+	CurrentProfile string `mapstructure:"current_profile"`
+
+	Profiles map[string]*Profile `mapstructure:"profiles"`
+}
+
+// Profile is a named set of settings which override the top level settings in the config file when selected.
+type Profile struct {
+	APIURL             string `mapstructure:"api_url"`
+	APIToken           string `mapstructure:"api_token"`
+	Organization       string `mapstructure:"organization"`
+	CredentialStore    string `mapstructure:"credential_store"`
+	CredentialHelper   string `mapstructure:"credential_helper"`
+	Retries            string `mapstructure:"retries"`
+	RetryMaxWait       string `mapstructure:"retry_max_wait"`
+	HTTPSProxy         string `mapstructure:"https_proxy"`
+	CABundle           string `mapstructure:"ca_bundle"`
+	ClientCert         string `mapstructure:"client_cert"`
+	ClientKey          string `mapstructure:"client_key"`
+	InsecureSkipVerify string `mapstructure:"insecure_skip_verify"`
+}
+
+// Used to get a pointer to the field of a key, or nil if the profile doesn't have the key.
+func (p *Profile) field(key string) *string {
+	v := reflect.ValueOf(p).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("mapstructure") == key {
+			return v.Field(i).Addr().Interface().(*string)
+		}
+	}
+	return nil
 }
 
 var Defaults = &Config{
-	APIURL:   "",
-	APIToken: "",
+	APIURL:       "",
+	APIToken:     "",
+	Organization: "",
 }
 
+// ConfigEnv is the environment variable which sets the path of the config file.
+const ConfigEnv = "KATAPULT_CONFIG"
+
 func New() (*Config, error) {
 	c := &Config{
 		viper: newViper(),
+		file:  newViper(),
+		flags: map[string]*pflag.Flag{},
 	}
 
 	c.SetDefault("api_url", Defaults.APIURL)
-	if err := c.BindEnv("url"); err != nil {
-		return nil, err
+	c.SetDefault("api_token", Defaults.APIToken)
+	c.SetDefault("organization", Defaults.Organization)
+
+	// Every key can be set with KATAPULT_ and the key in upper case, such as KATAPULT_API_URL.
+	for _, key := range append(Keys(), "profile") {
+		if err := c.BindEnv(key); err != nil {
+			return nil, err
+		}
 	}
 
-	c.SetDefault("api_token", Defaults.APIToken)
-	if err := c.BindEnv("api_token"); err != nil {
-		return nil, err
+	if fp := os.Getenv(ConfigEnv); fp != "" {
+		c.SetConfigFile(fp)
 	}
 
 	return c, nil
@@ -42,14 +139,301 @@ func New() (*Config, error) {
 
 func (c *Config) Load() error {
 	err := c.viper.ReadInConfig()
+	if err != nil && !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+		return err
+	}
+	if fp := c.viper.ConfigFileUsed(); err == nil && fp != "" {
+		c.file.SetConfigFile(fp)
+		if err = c.file.ReadInConfig(); err != nil {
+			return err
+		}
+	}
+
+	if err = c.viper.Unmarshal(c, viper.DecodeHook(flagValueToString)); err != nil {
+		return err
+	}
+	// An unknown profile doesn't stop the config from loading, so that it can still be fixed with the config
+	// commands. Other commands check ProfileError before they run.
+	c.profileErr = c.applyProfile()
+
+	// The same goes for a credential store which can't be read, so that the token can still be removed from it
+	// with "auth logout" or replaced with "auth login". Commands which use the token check TokenError.
+	c.tokenErr = c.loadToken()
+	return nil
+}
+
+// Used to turn the values of typed flags, such as bools and durations, into the strings which Config holds.
+// Without this, a bool flag would be decoded as 1 or 0.
+func flagValueToString(_ reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to.Kind() != reflect.String {
+		return data, nil
+	}
+	switch v := data.(type) {
+	case bool, int, time.Duration:
+		return fmt.Sprint(v), nil
+	default:
+		return data, nil
+	}
+}
+
+// ProfileError returns the error from applying the active profile when the config was loaded, such as the profile
+// not existing.
+func (c *Config) ProfileError() error {
+	return c.profileErr
+}
+
+// Used to apply the settings of the active profile. Settings set with flags or environment variables still take
+// priority over the profile.
+func (c *Config) applyProfile() error {
+	name := c.ProfileName()
+	if name == DefaultProfile {
+		return nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("the profile %s does not exist", name)
+	}
+
+	for _, key := range Keys() {
+		value := p.field(key)
+		if value == nil || *value == "" || c.isOverridden(key) {
+			continue
+		}
+		f, err := c.field(key)
+		if err != nil {
+			return err
+		}
+		*f = *value
+	}
+	return nil
+}
+
+// Used to check if a key was set with a flag or environment variable.
+func (c *Config) isOverridden(key string) bool {
+	if f, ok := c.flags[key]; ok && f.Changed {
+		return true
+	}
+	_, ok := os.LookupEnv("KATAPULT_" + strings.ToUpper(key))
+	return ok
+}
+
+// Defines the keys which are in Config but are managed by the profile functions rather than being set directly.
+var profileKeys = map[string]bool{"profile": true, "current_profile": true, "profiles": true}
+
+// Keys returns the keys which can be set, got and unset. These are the string fields of Config.
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := f.Tag.Get("mapstructure")
+		if key != "" && f.Type.Kind() == reflect.String && !profileKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Used to get a pointer to the field of a key, or an error if the key is unknown.
+func (c *Config) field(key string) (*string, error) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("mapstructure") == key && !profileKeys[key] && t.Field(i).Type.Kind() == reflect.String {
+			return v.Field(i).Addr().Interface().(*string), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown config key %s, must be one of: %s", key, strings.Join(Keys(), ", "))
+}
+
+// Get returns the value of a key after flags, environment variables and the active profile are applied.
+func (c *Config) Get(key string) (string, error) {
+	f, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	return *f, nil
+}
+
+// SetValue sets the value of a key in the active profile. The config needs to be written for this to be saved.
+func (c *Config) SetValue(key, value string) error {
+	f, err := c.field(key)
 	if err != nil {
-		if errors.As(err, &viper.ConfigFileNotFoundError{}) {
-			return c.viper.Unmarshal(c)
+		return err
+	}
+	if err = c.validateValue(key, value); err != nil {
+		return err
+	}
+	*f = value
+	c.SetProfileValue(key, value)
+	return nil
+}
+
+// Used to check that a value can be used for a key. A blank value is always allowed, since it means the default
+// is used.
+func (c *Config) validateValue(key, value string) error {
+	if value == "" {
+		return nil
+	}
+	var want string
+	switch key {
+	case "api_url", "https_proxy":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			want = "a URL such as https://example.com"
+		}
+	case "retries":
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			want = "a number which is 0 or more"
 		}
+	case "retry_max_wait":
+		if d, err := time.ParseDuration(value); err != nil || d < 0 {
+			want = "a duration such as 30s"
+		}
+	case "insecure_skip_verify":
+		if _, err := strconv.ParseBool(value); err != nil {
+			want = "true or false"
+		}
+	case "credential_store":
+		names := []string{PlaintextCredentialStore}
+		for _, store := range c.CredentialStores() {
+			names = append(names, store.Name())
+		}
+		if _, err := c.credentialStore(value); err != nil {
+			want = "one of: " + strings.Join(names, ", ")
+		}
+	}
+	if want != "" {
+		return fmt.Errorf("invalid value %s for %s, must be %s", value, key, want)
+	}
+	return nil
+}
+
+// UnsetValue removes a key from the active profile. The config needs to be written for this to be saved.
+func (c *Config) UnsetValue(key string) error {
+	if _, err := c.field(key); err != nil {
 		return err
 	}
+	return c.unsetFileKey(key)
+}
+
+// Used to remove a key of the active profile from the config file.
+func (c *Config) unsetFileKey(key string) error {
+	path := []string{key}
+	if name := c.ProfileName(); name != DefaultProfile {
+		path = []string{"profiles", name, key}
+	}
 
-	return c.viper.Unmarshal(c)
+	// Viper can't remove keys, so the file settings are rebuilt without the key.
+	settings := c.file.AllSettings()
+	m := settings
+	for _, k := range path[:len(path)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		m = next
+	}
+	delete(m, path[len(path)-1])
+	if len(path) > 1 && len(m) == 0 {
+		// Viper drops empty maps, so the key is kept blank to stop the profile from being removed.
+		m[key] = ""
+	}
+	file := newViper()
+	if fp := c.file.ConfigFileUsed(); fp != "" {
+		file.SetConfigFile(fp)
+	}
+	if err := file.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	c.file = file
+	return nil
+}
+
+// ProfileName returns the name of the active profile. The profile set with a flag or environment variable takes
+// priority over the one set with "config use-profile".
+func (c *Config) ProfileName() string {
+	switch {
+	case c.Profile != "":
+		return c.Profile
+	case c.CurrentProfile != "":
+		return c.CurrentProfile
+	default:
+		return DefaultProfile
+	}
+}
+
+// ProfileNames returns the sorted names of all profiles, including the default profile.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles)+1)
+	for name := range c.Profiles {
+		if name != DefaultProfile {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return append([]string{DefaultProfile}, names...)
+}
+
+// GetProfile returns the settings of a profile as they are in the config file. For the default profile, these are
+// the top level settings.
+func (c *Config) GetProfile(name string) (*Profile, bool) {
+	if name == DefaultProfile {
+		p := &Profile{}
+		for _, key := range Keys() {
+			if f := p.field(key); f != nil {
+				*f = c.file.GetString(key)
+			}
+		}
+		return p, true
+	}
+	p, ok := c.Profiles[name]
+	return p, ok
+}
+
+// UseProfile sets the profile which is used when one is not set with a flag or environment variable.
+func (c *Config) UseProfile(name string) error {
+	if name == DefaultProfile {
+		name = ""
+	} else if _, ok := c.Profiles[name]; !ok {
+		return fmt.Errorf("the profile %s does not exist", name)
+	}
+	c.CurrentProfile = name
+	c.Set("current_profile", name)
+	return nil
+}
+
+// SetProfileValue sets a key within the active profile, or the top level of the config if the default profile
+// is active.
+func (c *Config) SetProfileValue(key string, value interface{}) {
+	name := c.ProfileName()
+	if name == DefaultProfile {
+		c.Set(key, value)
+		return
+	}
+	c.Set("profiles."+name+"."+key, value)
+}
+
+// CreateProfile creates a profile with no settings if it doesn't already exist.
+func (c *Config) CreateProfile(name string) error {
+	if name == DefaultProfile {
+		return nil
+	}
+	if _, ok := c.Profiles[name]; ok {
+		return nil
+	}
+	if strings.ContainsAny(name, ". ") || name == "" {
+		return fmt.Errorf("invalid profile name: %q", name)
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]*Profile{}
+	}
+	c.Profiles[name] = &Profile{}
+
+	// Viper doesn't write empty maps, so the keys are written with blank values.
+	for _, key := range []string{"api_url", "api_token", "organization"} {
+		c.Set("profiles."+name+"."+key, "")
+	}
+	return nil
 }
 
 func (c *Config) AllSettings() map[string]interface{} {
@@ -57,6 +441,9 @@ func (c *Config) AllSettings() map[string]interface{} {
 }
 
 func (c *Config) ConfigFileUsed() string {
+	if fp := c.file.ConfigFileUsed(); fp != "" {
+		return fp
+	}
 	return c.viper.ConfigFileUsed()
 }
 
@@ -64,12 +451,15 @@ func (c *Config) SetDefault(key string, value interface{}) {
 	c.viper.SetDefault(key, value)
 }
 
+// Set is used to set a value. This value is included when the config is written.
 func (c *Config) Set(key string, value interface{}) {
 	c.viper.Set(key, value)
+	c.file.Set(key, value)
 }
 
 func (c *Config) SetConfigFile(file string) {
 	c.viper.SetConfigFile(file)
+	c.file.SetConfigFile(file)
 }
 
 func (c *Config) BindEnv(name string) error {
@@ -85,6 +475,9 @@ func (c *Config) BindFlagValues(flags viper.FlagValueSet) error {
 }
 
 func (c *Config) BindPFlag(key string, flag *pflag.Flag) error {
+	if flag != nil {
+		c.flags[key] = flag
+	}
 	return c.viper.BindPFlag(key, flag)
 }
 
@@ -94,7 +487,7 @@ func (c *Config) BindPFlags(flags *pflag.FlagSet) error {
 
 func (c *Config) WriteConfig() error {
 	//nolint:errorlint
-	switch err := c.viper.WriteConfig().(type) {
+	switch err := c.file.WriteConfig().(type) {
 	case viper.ConfigFileNotFoundError:
 		homedir, e := os.UserHomeDir()
 		if e != nil {
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -32,3 +36,223 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestNew_Env(t *testing.T) {
+	fp := writeConfigFile(t, "api_url: https://file.katapult.io\napi_token: file-token\n")
+	env := map[string]string{
+		"KATAPULT_CONFIG":       fp,
+		"KATAPULT_API_URL":      "https://env.katapult.io",
+		"KATAPULT_ORGANIZATION": "loge",
+	}
+	for k, v := range env {
+		require.NoError(t, os.Setenv(k, v))
+	}
+	defer func() {
+		for k := range env {
+			_ = os.Unsetenv(k)
+		}
+	}()
+
+	c, err := New()
+	require.NoError(t, err)
+	require.NoError(t, c.Load())
+	assert.Equal(t, fp, c.ConfigFileUsed())
+	assert.Equal(t, "https://env.katapult.io", c.APIURL)
+	assert.Equal(t, "file-token", c.APIToken)
+	assert.Equal(t, "loge", c.Organization)
+}
+
+// Defines a config file with profiles.
+const profilesConfig = `api_url: https://api.katapult.io
+api_token: top-level-token
+organization: loge
+current_profile: staging
+profiles:
+  staging:
+    api_url: https://staging.katapult.io
+    api_token: staging-token
+  other:
+    api_token: other-token
+    organization: org_1
+`
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	fp := filepath.Join(t.TempDir(), "katapult.yaml")
+	require.NoError(t, ioutil.WriteFile(fp, []byte(content), 0o600))
+	return fp
+}
+
+func TestConfig_Profiles(t *testing.T) {
+	tests := []struct {
+		name string
+
+		content string
+		env     map[string]string
+		flags   []string
+		want    *Profile
+		profile string
+		wantErr string
+	}{
+		{
+			name:    "no profiles",
+			content: "api_token: abc\n",
+			want:    &Profile{APIToken: "abc"},
+			profile: DefaultProfile,
+		},
+		{
+			name:    "current profile",
+			content: profilesConfig,
+			want: &Profile{
+				APIURL:       "https://staging.katapult.io",
+				APIToken:     "staging-token",
+				Organization: "loge",
+			},
+			profile: "staging",
+		},
+		{
+			name:    "profile from env",
+			content: profilesConfig,
+			env:     map[string]string{"KATAPULT_PROFILE": "other"},
+			want: &Profile{
+				APIURL:       "https://api.katapult.io",
+				APIToken:     "other-token",
+				Organization: "org_1",
+			},
+			profile: "other",
+		},
+		{
+			name:    "profile from flag",
+			content: profilesConfig,
+			env:     map[string]string{"KATAPULT_PROFILE": "other"},
+			flags:   []string{"--profile", "default"},
+			want: &Profile{
+				APIURL:       "https://api.katapult.io",
+				APIToken:     "top-level-token",
+				Organization: "loge",
+			},
+			profile: DefaultProfile,
+		},
+		{
+			name:    "env overrides profile",
+			content: profilesConfig,
+			env:     map[string]string{"KATAPULT_API_TOKEN": "env-token"},
+			want: &Profile{
+				APIURL:       "https://staging.katapult.io",
+				APIToken:     "env-token",
+				Organization: "loge",
+			},
+			profile: "staging",
+		},
+		{
+			name:    "flag overrides profile",
+			content: profilesConfig,
+			flags:   []string{"--api-token", "flag-token"},
+			want: &Profile{
+				APIURL:       "https://staging.katapult.io",
+				APIToken:     "flag-token",
+				Organization: "loge",
+			},
+			profile: "staging",
+		},
+		{
+			name:    "unknown profile",
+			content: profilesConfig,
+			flags:   []string{"--profile", "unknown"},
+			wantErr: "the profile unknown does not exist",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				require.NoError(t, os.Setenv(k, v))
+			}
+			defer func() {
+				for k := range tt.env {
+					_ = os.Unsetenv(k)
+				}
+			}()
+
+			c, err := New()
+			require.NoError(t, err)
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("profile", "", "")
+			flags.String("api-token", "", "")
+			require.NoError(t, c.BindPFlag("profile", flags.Lookup("profile")))
+			require.NoError(t, c.BindPFlag("api_token", flags.Lookup("api-token")))
+			require.NoError(t, flags.Parse(tt.flags))
+			c.SetConfigFile(writeConfigFile(t, tt.content))
+
+			require.NoError(t, c.Load())
+			if tt.wantErr != "" {
+				assert.EqualError(t, c.ProfileError(), tt.wantErr)
+				return
+			}
+			require.NoError(t, c.ProfileError())
+			assert.Equal(t, tt.want, &Profile{APIURL: c.APIURL, APIToken: c.APIToken, Organization: c.Organization})
+			assert.Equal(t, tt.profile, c.ProfileName())
+		})
+	}
+}
+
+func TestConfig_WriteConfig(t *testing.T) {
+	require.NoError(t, os.Setenv("KATAPULT_API_URL", "https://env.katapult.io"))
+	defer func() { _ = os.Unsetenv("KATAPULT_API_URL") }()
+
+	c, err := New()
+	require.NoError(t, err)
+	fp := writeConfigFile(t, profilesConfig)
+	c.SetConfigFile(fp)
+	require.NoError(t, c.Load())
+
+	// Set a value in the active profile, create a profile and switch to it.
+	c.SetProfileValue("api_token", "new-token")
+	require.NoError(t, c.CreateProfile("new"))
+	require.NoError(t, c.UseProfile("new"))
+	require.NoError(t, c.WriteConfig())
+
+	// Check the file only contains what was in it before plus what was set.
+	b, err := ioutil.ReadFile(fp)
+	require.NoError(t, err)
+	want := `api_token: top-level-token
+api_url: https://api.katapult.io
+current_profile: new
+organization: loge
+profiles:
+  new:
+    api_token: ""
+    api_url: ""
+    organization: ""
+  other:
+    api_token: other-token
+    organization: org_1
+  staging:
+    api_token: new-token
+    api_url: https://staging.katapult.io
+`
+	assert.Equal(t, want, string(b))
+}
+
+func TestConfig_GetProfile(t *testing.T) {
+	c, err := New()
+	require.NoError(t, err)
+	c.SetConfigFile(writeConfigFile(t, profilesConfig+"retries: \"5\"\nca_bundle: /etc/ca.pem\n"))
+	require.NoError(t, c.Load())
+
+	p, ok := c.GetProfile(DefaultProfile)
+	require.True(t, ok)
+	assert.Equal(t, &Profile{
+		APIURL:       "https://api.katapult.io",
+		APIToken:     "top-level-token",
+		Organization: "loge",
+		Retries:      "5",
+		CABundle:     "/etc/ca.pem",
+	}, p)
+
+	p, ok = c.GetProfile("staging")
+	require.True(t, ok)
+	assert.Equal(t, &Profile{APIURL: "https://staging.katapult.io", APIToken: "staging-token"}, p)
+
+	_, ok = c.GetProfile("unknown")
+	assert.False(t, ok)
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Defines the JSON which is written to the stdin of a credential helper.
+type credentialHelperRequest struct {
+	Profile string `json:"profile"`
+	APIURL  string `json:"api_url,omitempty"`
+	Token   string `json:"token,omitempty"`
+}
+
+// Defines the JSON which a credential helper writes to stdout for the get action.
+type credentialHelperResponse struct {
+	Token string `json:"token"`
+}
+
+// Used to store tokens with an external command, like git credential helpers. The action (get, store or erase)
+// is added as the last argument of the command, and a JSON request is written to its stdin. For get, the helper
+// should write a JSON response to stdout, with a blank token if it doesn't have one.
+type helperCredentialStore struct {
+	command string
+	apiURL  string
+}
+
+func (s *helperCredentialStore) Name() string {
+	return "helper"
+}
+
+func (s *helperCredentialStore) Available() bool {
+	return s.command != ""
+}
+
+// Used to run the helper with an action.
+func (s *helperCredentialStore) run(action string, req *credentialHelperRequest) ([]byte, error) {
+	args := strings.Fields(s.command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("credential_helper is not set")
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:gosec // The command comes from the users config.
+	cmd := exec.Command(args[0], append(args[1:], action)...)
+	cmd.Stdin = bytes.NewReader(b)
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("credential helper %s failed: %w: %s", action, err, msg)
+		}
+		return nil, fmt.Errorf("credential helper %s failed: %w", action, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s *helperCredentialStore) Get(profile string) (string, error) {
+	b, err := s.run("get", &credentialHelperRequest{Profile: profile, APIURL: s.apiURL})
+	if err != nil {
+		return "", err
+	}
+	var resp credentialHelperResponse
+	if err = json.Unmarshal(b, &resp); err != nil {
+		return "", fmt.Errorf("credential helper get returned invalid JSON: %w", err)
+	}
+	if resp.Token == "" {
+		return "", ErrCredentialNotFound
+	}
+	return resp.Token, nil
+}
+
+func (s *helperCredentialStore) Set(profile, token string) error {
+	_, err := s.run("store", &credentialHelperRequest{Profile: profile, APIURL: s.apiURL, Token: token})
+	return err
+}
+
+func (s *helperCredentialStore) Delete(profile string) error {
+	_, err := s.run("erase", &credentialHelperRequest{Profile: profile, APIURL: s.apiURL})
+	return err
+}
@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/krystal/go-katapult"
+)
+
+// ErrCredentialNotFound is returned when a credential store doesn't have a token for a profile.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// PlaintextCredentialStore is the name used for storing the token in the config file.
+const PlaintextCredentialStore = "plaintext"
+
+// CredentialStore is used to store API tokens outside of the config file. Tokens are stored by profile name and
+// API URL, so profiles with the same name for different APIs don't share a token.
+type CredentialStore interface {
+	// Name returns the name of the store. This is what credential_store is set to in the config.
+	Name() string
+
+	// Available returns whether the store can be used on this machine.
+	Available() bool
+
+	// Get returns the token for a profile. ErrCredentialNotFound is returned if there isn't one.
+	Get(profile string) (string, error)
+
+	// Set stores the token for a profile.
+	Set(profile, token string) error
+
+	// Delete removes the token for a profile. This is not an error if there isn't one.
+	Delete(profile string) error
+}
+
+// Used to get the account a token is stored under by the keyring and encrypted file stores, such as
+// default@https://api.katapult.io.
+func credentialAccount(profile, apiURL string) string {
+	if apiURL == "" {
+		apiURL = katapult.DefaultURL.String()
+	}
+	return profile + "@" + apiURL
+}
+
+// Used to get the path of the encrypted credentials file. This is next to the config file.
+func (c *Config) credentialsPath() string {
+	if fp := c.ConfigFileUsed(); fp != "" {
+		return filepath.Join(filepath.Dir(fp), "credentials.enc")
+	}
+	homedir, err := os.UserHomeDir()
+	if err != nil {
+		return "credentials.enc"
+	}
+	return filepath.Join(homedir, ".katapult", "credentials.enc")
+}
+
+// CredentialStores returns the credential stores in order of preference.
+func (c *Config) CredentialStores() []CredentialStore {
+	return []CredentialStore{
+		&helperCredentialStore{command: c.CredentialHelper, apiURL: c.APIURL},
+		keyringCredentialStore{apiURL: c.APIURL},
+		&encryptedFileCredentialStore{
+			path:       c.credentialsPath(),
+			passphrase: os.Getenv("KATAPULT_CREDENTIALS_PASSPHRASE"),
+			apiURL:     c.APIURL,
+		},
+	}
+}
+
+// Used to get a credential store by name. A nil store is returned for the plaintext store.
+func (c *Config) credentialStore(name string) (CredentialStore, error) {
+	if name == PlaintextCredentialStore {
+		return nil, nil
+	}
+	for _, store := range c.CredentialStores() {
+		if store.Name() == name {
+			return store, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown credential store %s", name)
+}
+
+// Used to get the token from the credential store of the active profile.
+func (c *Config) loadToken() error {
+	if c.CredentialStore == "" || c.isOverridden("api_token") {
+		return nil
+	}
+	store, err := c.credentialStore(c.CredentialStore)
+	if err != nil || store == nil {
+		return err
+	}
+	token, err := store.Get(c.ProfileName())
+	switch {
+	case errors.Is(err, ErrCredentialNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to get the API token from the %s credential store: %w", store.Name(), err)
+	default:
+		c.APIToken = token
+		return nil
+	}
+}
+
+// TokenError returns the error from getting the API token from the credential store when the config was loaded.
+// The token is blank if this isn't nil.
+func (c *Config) TokenError() error {
+	return c.tokenErr
+}
+
+// SaveToken stores the token of the active profile. If credential_store is set, that store is used. Otherwise,
+// the first available store is used, falling back to the config file if none are available. The name of the
+// store used is returned. The config needs to be written afterwards.
+func (c *Config) SaveToken(token string) (string, error) {
+	var store CredentialStore
+	if c.CredentialStore == "" {
+		for _, s := range c.CredentialStores() {
+			if s.Available() {
+				store = s
+				break
+			}
+		}
+	} else {
+		var err error
+		store, err = c.credentialStore(c.CredentialStore)
+		if err != nil {
+			return "", err
+		}
+		if store != nil && !store.Available() {
+			return "", fmt.Errorf("the %s credential store is not available", store.Name())
+		}
+	}
+
+	c.APIToken = token
+	c.tokenErr = nil
+	if store == nil {
+		c.SetProfileValue("api_token", token)
+		return PlaintextCredentialStore, nil
+	}
+
+	if err := store.Set(c.ProfileName(), token); err != nil {
+		return "", err
+	}
+	c.SetProfileValue("credential_store", store.Name())
+	c.CredentialStore = store.Name()
+	return store.Name(), c.unsetFileKey("api_token")
+}
+
+// DeleteToken removes the token of the active profile from its credential store and the config file. The name of
+// the store the token was removed from is returned. The config needs to be written afterwards.
+func (c *Config) DeleteToken() (string, error) {
+	name := PlaintextCredentialStore
+	if c.CredentialStore != "" {
+		store, err := c.credentialStore(c.CredentialStore)
+		if err != nil {
+			return "", err
+		}
+		if store != nil {
+			if err = store.Delete(c.ProfileName()); err != nil {
+				return "", err
+			}
+			name = store.Name()
+		}
+	}
+	c.APIToken = ""
+	c.tokenErr = nil
+	return name, c.unsetFileKey("api_token")
+}
+
+// TokenSource returns where the API token of the active profile comes from. This is either the name of a
+// credential store, or "override" when it is set with a flag or environment variable. A blank string is returned
+// if there isn't a token.
+func (c *Config) TokenSource() string {
+	switch {
+	case c.APIToken == "":
+		return ""
+	case c.isOverridden("api_token"):
+		return "override"
+	case c.CredentialStore != "":
+		return c.CredentialStore
+	default:
+		return PlaintextCredentialStore
+	}
+}
@@ -0,0 +1,294 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEncryptedFileCredentialStore_key(t *testing.T) {
+	// The key must not change, or existing credentials files can't be read.
+	store := &encryptedFileCredentialStore{passphrase: "hunter2"}
+	assert.Equal(t, "84b29ea79e4c53a6f54d77e6fad377276a71e497bb4a2e6860a2b476c8f01f56",
+		hex.EncodeToString(store.key([]byte("0123456789abcdef"))))
+}
+
+// Used to check a credential store can set, get and delete tokens.
+func assertCredentialStore(t *testing.T, store CredentialStore) {
+	t.Helper()
+	require.True(t, store.Available())
+
+	_, err := store.Get("default")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+
+	require.NoError(t, store.Set("default", "abc"))
+	require.NoError(t, store.Set("staging", "def"))
+	token, err := store.Get("default")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", token)
+	token, err = store.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "def", token)
+
+	require.NoError(t, store.Delete("default"))
+	require.NoError(t, store.Delete("default"))
+	_, err = store.Get("default")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+	token, err = store.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "def", token)
+}
+
+func TestKeyringCredentialStore(t *testing.T) {
+	keyring.MockInit()
+	assertCredentialStore(t, keyringCredentialStore{})
+
+	// Tokens are per API URL.
+	staging := keyringCredentialStore{apiURL: "https://staging.katapult.io"}
+	_, err := staging.Get("staging")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+	require.NoError(t, staging.Set("staging", "ghi"))
+	token, err := keyringCredentialStore{}.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "def", token)
+	token, err = staging.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "ghi", token)
+}
+
+func TestEncryptedFileCredentialStore(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), "credentials.enc")
+	assertCredentialStore(t, &encryptedFileCredentialStore{path: fp, passphrase: "hunter2"})
+
+	// The token should not be readable in the file.
+	b, err := ioutil.ReadFile(fp)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "def")
+
+	// Tokens are per API URL.
+	staging := &encryptedFileCredentialStore{path: fp, passphrase: "hunter2", apiURL: "https://staging.katapult.io"}
+	_, err = staging.Get("staging")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+	require.NoError(t, staging.Set("staging", "ghi"))
+	token, err := (&encryptedFileCredentialStore{path: fp, passphrase: "hunter2"}).Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "def", token)
+	token, err = staging.Get("staging")
+	require.NoError(t, err)
+	assert.Equal(t, "ghi", token)
+
+	// The wrong passphrase should fail.
+	_, err = (&encryptedFileCredentialStore{path: fp, passphrase: "hunter3"}).Get("staging")
+	assert.EqualError(t, err, "failed to decrypt the credentials file, is the passphrase correct?")
+
+	// No passphrase means the store isn't available.
+	assert.False(t, (&encryptedFileCredentialStore{path: fp}).Available())
+}
+
+// TestHelperProcess isn't a real test. It is used as a credential helper by the tests, storing tokens as JSON in
+// the file in KATAPULT_TEST_HELPER_FILE. Getting the token "locked" fails.
+func TestHelperProcess(t *testing.T) {
+	fp := os.Getenv("KATAPULT_TEST_HELPER_FILE")
+	if fp == "" {
+		return
+	}
+	defer os.Exit(0)
+
+	var req credentialHelperRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	tokens := map[string]string{}
+	if b, err := ioutil.ReadFile(fp); err == nil {
+		_ = json.Unmarshal(b, &tokens)
+	}
+	key := req.APIURL + "|" + req.Profile
+	switch os.Args[len(os.Args)-1] {
+	case "get":
+		if tokens[key] == "locked" {
+			fmt.Fprintln(os.Stderr, "the store is locked")
+			os.Exit(1)
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(&credentialHelperResponse{Token: tokens[key]})
+		return
+	case "store":
+		if req.Token == "fail" {
+			fmt.Fprintln(os.Stderr, "refusing to store the token")
+			os.Exit(1)
+		}
+		tokens[key] = req.Token
+	case "erase":
+		delete(tokens, key)
+	}
+	b, _ := json.Marshal(tokens)
+	_ = ioutil.WriteFile(fp, b, 0o600)
+}
+
+// Used to get a credential helper command which runs TestHelperProcess.
+func testHelperCommand(t *testing.T) string {
+	t.Helper()
+	require.NoError(t, os.Setenv("KATAPULT_TEST_HELPER_FILE", filepath.Join(t.TempDir(), "helper.json")))
+	t.Cleanup(func() { _ = os.Unsetenv("KATAPULT_TEST_HELPER_FILE") })
+	return os.Args[0] + " -test.run=TestHelperProcess --"
+}
+
+func TestHelperCredentialStore(t *testing.T) {
+	command := testHelperCommand(t)
+	assertCredentialStore(t, &helperCredentialStore{command: command, apiURL: "https://api.katapult.io"})
+
+	// Tokens are per API URL in the test helper.
+	_, err := (&helperCredentialStore{command: command}).Get("staging")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+
+	// Errors from the helper should be passed through.
+	err = (&helperCredentialStore{command: command}).Set("default", "fail")
+	assert.EqualError(t, err, "credential helper store failed: exit status 1: refusing to store the token")
+
+	// No command means the store isn't available.
+	assert.False(t, (&helperCredentialStore{}).Available())
+}
+
+func TestConfig_SaveToken(t *testing.T) {
+	keyring.MockInit()
+	helper := testHelperCommand(t)
+	tests := []struct {
+		name string
+
+		content   string
+		env       map[string]string
+		wantStore string
+		want      string
+		wantErr   string
+	}{
+		{
+			name:      "keyring",
+			content:   "api_token: old-token\n",
+			wantStore: "keyring",
+			want:      "credential_store: keyring\n",
+		},
+		{
+			name:      "credential helper",
+			content:   "credential_helper: " + helper + "\n",
+			wantStore: "helper",
+			want:      "credential_helper: " + helper + "\ncredential_store: helper\n",
+		},
+		{
+			name:      "encrypted file",
+			content:   "credential_store: file\n",
+			env:       map[string]string{"KATAPULT_CREDENTIALS_PASSPHRASE": "hunter2"},
+			wantStore: "file",
+			want:      "credential_store: file\n",
+		},
+		{
+			name:      "encrypted file without passphrase",
+			content:   "credential_store: file\n",
+			wantErr:   "the file credential store is not available",
+			wantStore: "file",
+		},
+		{
+			name:      "plaintext",
+			content:   "credential_store: plaintext\n",
+			wantStore: "plaintext",
+			want:      "api_token: new-token\ncredential_store: plaintext\n",
+		},
+		{
+			name:      "profile",
+			content:   "current_profile: staging\nprofiles:\n  staging:\n    api_token: old-token\n",
+			wantStore: "keyring",
+			want:      "current_profile: staging\nprofiles:\n  staging:\n    credential_store: keyring\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				require.NoError(t, os.Setenv(k, v))
+			}
+			defer func() {
+				for k := range tt.env {
+					_ = os.Unsetenv(k)
+				}
+			}()
+
+			c, err := New()
+			require.NoError(t, err)
+			fp := writeConfigFile(t, tt.content)
+			c.SetConfigFile(fp)
+			require.NoError(t, c.Load())
+
+			store, err := c.SaveToken("new-token")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStore, store)
+			require.NoError(t, c.WriteConfig())
+			b, err := ioutil.ReadFile(fp)
+			require.NoError(t, err)
+			var want, got map[string]interface{}
+			require.NoError(t, yaml.Unmarshal([]byte(tt.want), &want))
+			require.NoError(t, yaml.Unmarshal(b, &got))
+			assert.Equal(t, want, got)
+
+			// Loading the config again should get the token from the store.
+			c, err = New()
+			require.NoError(t, err)
+			c.SetConfigFile(fp)
+			require.NoError(t, c.Load())
+			assert.Equal(t, "new-token", c.APIToken)
+		})
+	}
+}
+
+func TestConfig_DeleteToken(t *testing.T) {
+	keyring.MockInit()
+	c, err := New()
+	require.NoError(t, err)
+	fp := writeConfigFile(t, "credential_store: keyring\n")
+	c.SetConfigFile(fp)
+	require.NoError(t, c.Load())
+	require.NoError(t, keyringCredentialStore{}.Set("default", "abc"))
+	require.NoError(t, c.Load())
+	assert.Equal(t, "abc", c.APIToken)
+	assert.Equal(t, "keyring", c.TokenSource())
+
+	store, err := c.DeleteToken()
+	require.NoError(t, err)
+	assert.Equal(t, "keyring", store)
+	assert.Empty(t, c.APIToken)
+	assert.Empty(t, c.TokenSource())
+	_, err = keyringCredentialStore{}.Get("default")
+	assert.ErrorIs(t, err, ErrCredentialNotFound)
+}
+
+func TestConfig_TokenError(t *testing.T) {
+	helper := testHelperCommand(t)
+	require.NoError(t, (&helperCredentialStore{command: helper}).Set("default", "locked"))
+	c, err := New()
+	require.NoError(t, err)
+	c.SetConfigFile(writeConfigFile(t, "credential_helper: "+helper+"\ncredential_store: helper\n"))
+
+	// The config still loads when the token can't be got.
+	require.NoError(t, c.Load())
+	assert.EqualError(t, c.TokenError(), "failed to get the API token from the helper credential store: "+
+		"credential helper get failed: exit status 1: the store is locked")
+	assert.Empty(t, c.APIToken)
+
+	// The token can still be removed.
+	store, err := c.DeleteToken()
+	require.NoError(t, err)
+	assert.Equal(t, "helper", store)
+	assert.NoError(t, c.TokenError())
+	require.NoError(t, c.Load())
+	assert.NoError(t, c.TokenError())
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// Defines the length of the salt used to derive the key.
+	encryptedFileSaltLength = 16
+
+	// Defines the number of PBKDF2 iterations used to derive the key.
+	encryptedFileIterations = 200000
+)
+
+// Used to store tokens in a file encrypted with AES-256-GCM. The key is derived from the passphrase in
+// KATAPULT_CREDENTIALS_PASSPHRASE, so this can be used on machines without a keyring. Like the keyring, tokens are
+// stored by profile and API URL.
+type encryptedFileCredentialStore struct {
+	path       string
+	passphrase string
+	apiURL     string
+}
+
+func (s *encryptedFileCredentialStore) Name() string {
+	return "file"
+}
+
+func (s *encryptedFileCredentialStore) Available() bool {
+	return s.passphrase != ""
+}
+
+// Used to derive the key for a salt from the passphrase with PBKDF2-HMAC-SHA256.
+func (s *encryptedFileCredentialStore) key(salt []byte) []byte {
+	return pbkdf2.Key([]byte(s.passphrase), salt, encryptedFileIterations, 32, sha256.New)
+}
+
+// Used to create the cipher for a salt.
+func (s *encryptedFileCredentialStore) aead(salt []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key(salt))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Used to read the tokens from the file. The file is made up of the salt, the nonce and then the encrypted JSON.
+func (s *encryptedFileCredentialStore) read() (map[string]string, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	if len(b) < encryptedFileSaltLength {
+		return nil, errors.New("the credentials file is corrupt")
+	}
+	aead, err := s.aead(b[:encryptedFileSaltLength])
+	if err != nil {
+		return nil, err
+	}
+	b = b[encryptedFileSaltLength:]
+	if len(b) < aead.NonceSize() {
+		return nil, errors.New("the credentials file is corrupt")
+	}
+	plaintext, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New("failed to decrypt the credentials file, is the passphrase correct?")
+	}
+	tokens := map[string]string{}
+	if err = json.Unmarshal(plaintext, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Used to write the tokens to the file. A new salt and nonce are used each time.
+func (s *encryptedFileCredentialStore) write(tokens map[string]string) error {
+	plaintext, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, encryptedFileSaltLength)
+	if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	aead, err := s.aead(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+	b := append(salt, nonce...)
+	b = aead.Seal(b, nonce, plaintext, nil)
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(s.path, b, 0o600)
+}
+
+func (s *encryptedFileCredentialStore) Get(profile string) (string, error) {
+	tokens, err := s.read()
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[credentialAccount(profile, s.apiURL)]
+	if !ok {
+		return "", ErrCredentialNotFound
+	}
+	return token, nil
+}
+
+func (s *encryptedFileCredentialStore) Set(profile, token string) error {
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[credentialAccount(profile, s.apiURL)] = token
+	return s.write(tokens)
+}
+
+func (s *encryptedFileCredentialStore) Delete(profile string) error {
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	account := credentialAccount(profile, s.apiURL)
+	if _, ok := tokens[account]; !ok {
+		return nil
+	}
+	delete(tokens, account)
+	return s.write(tokens)
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Defines the service name which tokens are stored under in the OS keyring.
+const keyringService = "katapult-cli"
+
+// Used to store tokens in the OS keyring (Secret Service on Linux, Keychain on macOS and Credential Manager on
+// Windows). Tokens are stored by profile and API URL, so profiles with the same name for different APIs don't
+// share a token.
+type keyringCredentialStore struct {
+	apiURL string
+}
+
+func (keyringCredentialStore) Name() string {
+	return "keyring"
+}
+
+// Available checks the keyring can be reached by looking up a token which doesn't exist.
+func (keyringCredentialStore) Available() bool {
+	_, err := keyring.Get(keyringService, "katapult-cli-availability-check")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+func (s keyringCredentialStore) Get(profile string) (string, error) {
+	token, err := keyring.Get(keyringService, credentialAccount(profile, s.apiURL))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrCredentialNotFound
+	}
+	return token, err
+}
+
+func (s keyringCredentialStore) Set(profile, token string) error {
+	return keyring.Set(keyringService, credentialAccount(profile, s.apiURL), token)
+}
+
+func (s keyringCredentialStore) Delete(profile string) error {
+	err := keyring.Delete(keyringService, credentialAccount(profile, s.apiURL))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -6,6 +6,7 @@ require (
 	github.com/augurysys/timestamp v0.0.0-20190827071116-44c56d926547
 	github.com/buger/goterm v1.0.1
 	github.com/fsnotify/fsnotify v1.4.9 // indirect
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/krystal/go-katapult v0.1.6-0.20210803112233-232e29436452
 	github.com/magiconair/properties v1.8.4 // indirect
 	github.com/mattn/go-runewidth v0.0.10 // indirect
@@ -19,9 +20,9 @@ require (
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.7.0
-	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
-	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
-	golang.org/x/text v0.3.4 // indirect
+	github.com/zalando/go-keyring v0.1.1
+	golang.org/x/crypto v0.1.0
+	golang.org/x/term v0.1.0
 	gopkg.in/ini.v1 v1.62.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 )